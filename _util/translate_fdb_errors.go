@@ -0,0 +1,136 @@
+// FoundationDB Go errors translator
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type errorDef struct {
+	Name string
+	Code int
+	Description string
+}
+
+var errorLine = regexp.MustCompile(`^\s*ERROR\(\s*(\w+)\s*,\s*(\d+)\s*,\s*"(.*)"\s*\)`)
+
+// Words that should be capitalized in their entirety in Go identifiers
+var initialisms = map[string]bool{
+	"api": true,
+	"db": true,
+	"dns": true,
+	"grv": true,
+	"http": true,
+	"id": true,
+	"io": true,
+	"tls": true,
+}
+
+func translateName(old string) string {
+	words := strings.Split(old, "_")
+	for i, w := range(words) {
+		if initialisms[w] {
+			words[i] = strings.ToUpper(w)
+		} else {
+			words[i] = strings.Title(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// lowerFirst lowercases the first letter of s, unless it begins an acronym
+func lowerFirst(s string) string {
+	if s == "" {
+		return ""
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	if r2, _ := utf8.DecodeRuneInString(s[n:]); unicode.IsUpper(r2) {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[n:]
+}
+
+func main() {
+	var defs []errorDef
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		m := errorLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		code, err := strconv.Atoi(m[2])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Success is not an error
+		if code == 0 {
+			continue
+		}
+
+		defs = append(defs, errorDef{m[1], code, m[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(`// DO NOT EDIT THIS FILE BY HAND. This file was generated using
+// translate_fdb_errors.go, part of the fdb-go repository, and a copy of the
+// error_definitions.h file (part of the FoundationDB source, found as
+// flow/error_definitions.h).
+
+// To regenerate this file, from the top level of an fdb-go repository checkout,
+// run:
+// $ go run _util/translate_fdb_errors.go < flow/error_definitions.h > fdb/generated_errors.go
+
+package fdb
+
+var (`)
+
+	for _, d := range(defs) {
+		fmt.Printf(`
+	// Err%s is error code %d (%s): %s.
+	Err%s = Error{%d}
+`, translateName(d.Name), d.Code, d.Name, lowerFirst(d.Description), translateName(d.Name), d.Code)
+	}
+
+	fmt.Print(`)
+
+var errorNames = map[int]string{
+`)
+
+	for _, d := range(defs) {
+		fmt.Printf("	%d: %q,\n", d.Code, d.Name)
+	}
+
+	fmt.Println("}")
+}
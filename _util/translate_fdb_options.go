@@ -23,14 +23,15 @@ package main
 
 import (
 	"encoding/xml"
+	"flag"
 	"io/ioutil"
 	"fmt"
 	"log"
 	"strings"
 	"os"
+	"sort"
 	"unicode"
 	"unicode/utf8"
-	"go/doc"
 )
 
 type Option struct {
@@ -39,6 +40,7 @@ type Option struct {
 	ParamType string `xml:"paramType,attr"`
 	ParamDesc string `xml:"paramDescription,attr"`
 	Description string `xml:"description,attr"`
+	Hidden bool `xml:"hidden,attr"`
 }
 type Scope struct {
 	Name string `xml:"name,attr"`
@@ -48,68 +50,167 @@ type Options struct {
 	Scope []Scope
 }
 
-func writeOptString(receiver string, function string, opt Option) {
+// apiVersions records, by scope and then by code, the API version in which
+// options newer than API version 200 were introduced. fdb.options does not
+// record this, so these are taken from the API version upgrade notes of the
+// FoundationDB documentation. The generated setters of these options return an
+// error, and these mutations cause the transaction to fail to commit, if an
+// earlier API version has been selected.
+var apiVersions = map[string]map[int]int{
+	"MutationType": {
+		14: 300, // set_versionstamped_key
+		15: 300, // set_versionstamped_value
+		16: 510, // byte_min
+		17: 510, // byte_max
+		20: 610, // compare_and_clear
+	},
+	"TransactionOption": {
+		600: 300, // snapshot_ryw_enable
+		601: 300, // snapshot_ryw_disable
+		712: 630, // report_conflicting_keys
+	},
+}
+
+// deprecation returns the deprecation notice of an option, if the option is
+// described as deprecated in fdb.options.
+func deprecation(opt Option) (string, bool) {
+	if !strings.HasPrefix(opt.Description, "Deprecated") {
+		return "", false
+	}
+	note := strings.TrimLeft(strings.TrimPrefix(opt.Description, "Deprecated"), " .:")
+	if note == "" {
+		note = "This option has been deprecated by FoundationDB."
+	}
+	return note, true
+}
+
+// docText returns the text of a description from fdb.options as it should
+// appear in a Go doc comment, without the reStructuredText markup of literals.
+func docText(s string) string {
+	return strings.Replace(s, "``", "", -1)
+}
+
+func versionCheck(version int) string {
+	if version == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`if e := requireAPIVersion(%d); e != nil {
+		return e
+	}
+	`, version)
+}
+
+func writeOptString(receiver string, function string, opt Option, version int) {
 	fmt.Printf(`func (o %s) %s(param string) error {
-	return o.setOpt(%d, []byte(param))
+	%sreturn o.setOpt(%d, []byte(param))
 }
-`, receiver, function, opt.Code)
+`, receiver, function, versionCheck(version), opt.Code)
 }
 
-func writeOptBytes(receiver string, function string, opt Option) {
+func writeOptBytes(receiver string, function string, opt Option, version int) {
 	fmt.Printf(`func (o %s) %s(param []byte) error {
-	return o.setOpt(%d, param)
+	%sreturn o.setOpt(%d, param)
 }
-`, receiver, function, opt.Code)
+`, receiver, function, versionCheck(version), opt.Code)
 }
 
-func writeOptInt(receiver string, function string, opt Option) {
+func writeOptInt(receiver string, function string, opt Option, version int) {
 	fmt.Printf(`func (o %s) %s(param int64) error {
-	b, e := int64ToBytes(param)
+	%sb, e := int64ToBytes(param)
 	if e != nil {
 		return e
 	}
 	return o.setOpt(%d, b)
 }
-`, receiver, function, opt.Code)
+`, receiver, function, versionCheck(version), opt.Code)
 }
 
-func writeOptNone(receiver string, function string, opt Option) {
+func writeOptNone(receiver string, function string, opt Option, version int) {
 	fmt.Printf(`func (o %s) %s() error {
-	return o.setOpt(%d, nil)
+	%sreturn o.setOpt(%d, nil)
 }
-`, receiver, function, opt.Code)
+`, receiver, function, versionCheck(version), opt.Code)
 }
 
-func writeOpt(receiver string, opt Option) {
+func writeOpt(scope Scope, opt Option) {
+	receiver := scope.Name + "s"
 	function := "Set" + translateName(opt.Name)
+	version := apiVersions[scope.Name][opt.Code]
 
 	fmt.Println()
 
-	if opt.Description != "" {
-		fmt.Printf("// %s\n", opt.Description)
-		if opt.ParamDesc != "" {
-			fmt.Printf("//\n// Parameter: %s\n", opt.ParamDesc)
-		}
+	note, deprecated := deprecation(opt)
+
+	if deprecated {
+		fmt.Printf("// %s is deprecated.\n", function)
+	} else if opt.Description != "" {
+		wrapComment(docText(opt.Description), "// ", 77)
 	} else {
 		fmt.Printf("// Not yet implemented.\n")
 	}
 
+	if opt.ParamDesc != "" && (deprecated || opt.Description != "") {
+		fmt.Println("//")
+		wrapComment("Parameter: " + docText(opt.ParamDesc), "// ", 77)
+	}
+
+	if version != 0 {
+		fmt.Printf("//\n// %s requires API version %d or later.\n", function, version)
+	}
+
+	if deprecated {
+		fmt.Println("//")
+		wrapComment("Deprecated: " + note, "// ", 77)
+	}
+
 	switch opt.ParamType {
 	case "String":
-		writeOptString(receiver, function, opt)
+		writeOptString(receiver, function, opt, version)
 	case "Bytes":
-		writeOptBytes(receiver, function, opt)
+		writeOptBytes(receiver, function, opt, version)
 	case "Int":
-		writeOptInt(receiver, function, opt)
+		writeOptInt(receiver, function, opt, version)
 	case "":
-		writeOptNone(receiver, function, opt)
+		writeOptNone(receiver, function, opt, version)
 	default:
 		log.Fatalf("Totally unexpected ParamType %s", opt.ParamType)
 	}
 }
 
+// initialisms are the words of option names that are written in upper case.
+var initialisms = map[string]string{
+	"tls": "TLS",
+}
+
 func translateName(old string) string {
-	return strings.Replace(strings.Title(strings.Replace(old, "_", " ", -1)), " ", "", -1)
+	words := strings.Split(old, "_")
+	for i, w := range words {
+		if s, ok := initialisms[w]; ok {
+			words[i] = s
+		} else {
+			words[i] = strings.Title(w)
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// wrapComment prints text as a comment with the given prefix, filling lines of
+// up to width characters (not counting the prefix).
+func wrapComment(text string, prefix string, width int) {
+	var line string
+	for _, w := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(w) > width {
+			fmt.Printf("%s%s\n", prefix, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" {
+		fmt.Printf("%s%s\n", prefix, line)
+	}
 }
 
 func lowerFirst (s string) string {
@@ -121,28 +222,216 @@ func lowerFirst (s string) string {
 }
 
 func writeMutation(opt Option) {
-	desc := lowerFirst(opt.Description)
 	tname := translateName(opt.Name)
-	fmt.Printf(`
-// %s %s
-func (t Transaction) %s(key KeyConvertible, param []byte) {
+	version := apiVersions["MutationType"][opt.Code]
+
+	fmt.Println()
+
+	note, deprecated := deprecation(opt)
+	if deprecated {
+		fmt.Printf("// %s is deprecated.\n", tname)
+	} else {
+		wrapComment(tname + " " + lowerFirst(docText(opt.Description)), "// ", 77)
+	}
+
+	if version != 0 {
+		fmt.Println("//")
+		wrapComment(fmt.Sprintf("%s requires API version %d or later. If an earlier API version has been selected, the mutation is not performed and the transaction fails to commit.", tname, version), "// ", 77)
+	}
+
+	if deprecated {
+		fmt.Println("//")
+		wrapComment("Deprecated: " + note, "// ", 77)
+	}
+
+	fmt.Printf(`func (t Transaction) %s(key KeyConvertible, param []byte) {
 	t.atomicOp(key.FDBKey(), param, %d)
 }
-`, tname, desc, tname, opt.Code)
+`, tname, opt.Code)
+}
+
+// writeMutationVersions writes the table of the API versions in which mutations
+// were introduced, which (Transaction).atomicOp checks.
+func writeMutationVersions() {
+	fmt.Printf(`
+// mutationAPIVersions records, by code, the API version in which mutations
+// newer than API version 200 were introduced.
+var mutationAPIVersions = map[int]int{
+`)
+	versions := apiVersions["MutationType"]
+	codes := make([]int, 0, len(versions))
+	for code := range(versions) {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range(codes) {
+		fmt.Printf("	%d: %d,\n", code, versions[code])
+	}
+	fmt.Println("}")
 }
 
 func writeEnum(scope Scope, opt Option, delta int) {
 	fmt.Println()
-	if opt.Description != "" {
-		doc.ToText(os.Stdout, opt.Description, "    // ", "", 73)
-		// fmt.Printf("	// %s\n", opt.Description)
+	if note, deprecated := deprecation(opt); deprecated {
+		wrapComment("Deprecated: " + note, "    // ", 73)
+	} else if opt.Description != "" {
+		wrapComment(docText(opt.Description), "    // ", 73)
 	}
 	fmt.Printf("	%s %s = %d\n", scope.Name + translateName(opt.Name), scope.Name, opt.Code + delta)
 }
 
+// writeErrorPredicateTest writes the Test method of ErrorPredicate, which
+// defers to the function of the fdb package named for each predicate.
+func writeErrorPredicateTest(scope Scope) {
+	fmt.Printf(`
+// Test returns true if e (or an error it wraps) satisfies the predicate. Each
+// predicate is tested by the correspondingly-named function of the fdb package;
+// for example, ErrorPredicateRetryable is tested by IsRetryable.
+func (p ErrorPredicate) Test(e error) bool {
+	switch p {
+`)
+	for _, opt := range(scope.Option) {
+		name := translateName(opt.Name)
+		fmt.Printf("	case ErrorPredicate%s:\n		return Is%s(e)\n", name, name)
+	}
+	fmt.Printf(`	}
+	return false
+}
+`)
+}
+
+// testReceivers are the expressions, within the generated test, yielding the
+// receiver of the setters of each option scope.
+var testReceivers = map[string]string{
+	"NetworkOption": "Options()",
+	"DatabaseOption": "db.Options()",
+	"TransactionOption": "tr.Options()",
+}
+
+// writeTestCase writes an entry in the table of the generated test, which
+// calls the setter of opt and expects the option to be set with its code and
+// the encoding of the argument passed.
+func writeTestCase(scope Scope, opt Option) {
+	receiver, ok := testReceivers[scope.Name]
+	if !ok {
+		log.Fatalf("No receiver for options of scope %s", scope.Name)
+	}
+
+	var arg, param string
+	switch opt.ParamType {
+	case "String":
+		arg, param = `"param"`, `[]byte("param")`
+	case "Bytes":
+		arg, param = `[]byte("param")`, `[]byte("param")`
+	case "Int":
+		arg, param = "42", "[]byte{42, 0, 0, 0, 0, 0, 0, 0}"
+	case "":
+		arg, param = "", "nil"
+	default:
+		log.Fatalf("Totally unexpected ParamType %s", opt.ParamType)
+	}
+
+	function := "Set" + translateName(opt.Name)
+	fmt.Printf("		{\"%ss.%s\", func() error { return %s.%s(%s) }, %d, %s},\n", scope.Name, function, receiver, function, arg, opt.Code, param)
+}
+
+func writeTest(v Options) {
+	fmt.Print(`// DO NOT EDIT THIS FILE BY HAND. This file was generated using
+// translate_fdb_options.go, part of the fdb-go repository, and a copy of the
+// fdb.options file (installed as part of the FoundationDB client, typically
+// found as /usr/include/foundationdb/fdb.options).
+
+// To regenerate this file, from the top level of an fdb-go repository checkout,
+// run:
+// $ go run _util/translate_fdb_options.go -test < /usr/include/foundationdb/fdb.options > fdb/generated_test.go
+
+package fdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+// optionRecorder is a DatabaseBackend and TransactionBackend that records the
+// last option set, or atomic operation performed, through it.
+type optionRecorder struct {
+	TransactionBackend
+	code int
+	param []byte
+}
+
+func (r *optionRecorder) CreateTransaction() (TransactionBackend, error) {
+	return r, nil
+}
+
+func (r *optionRecorder) SetOption(code int, param []byte) error {
+	r.code, r.param = code, param
+	return nil
+}
+
+func (r *optionRecorder) AtomicOp(key Key, param []byte, code int) {
+	r.code, r.param = code, param
+}
+
+func TestGeneratedSetters(t *testing.T) {
+	r := &optionRecorder{}
+
+	setter := networkOptionSetter
+	defer func() { networkOptionSetter = setter }()
+	networkOptionSetter = r.SetOption
+
+	db := NewDatabase(r)
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tests := []struct {
+		name string
+		set func() error
+		code int
+		param []byte
+	}{
+`)
+
+	for _, scope := range(v.Scope) {
+		if strings.HasSuffix(scope.Name, "Option") {
+			for _, opt := range(scope.Option) {
+				writeTestCase(scope, opt)
+			}
+		}
+
+		if scope.Name == "MutationType" {
+			for _, opt := range(scope.Option) {
+				name := translateName(opt.Name)
+				fmt.Printf("		{\"Transaction.%s\", func() error { tr.%s(Key(\"key\"), []byte(\"param\")); return nil }, %d, []byte(\"param\")},\n", name, name, opt.Code)
+			}
+		}
+	}
+
+	/* Not fmt.Print, to keep vet from taking these for formatting directives */
+	os.Stdout.WriteString(`	}
+
+	for _, tt := range tests {
+		r.code, r.param = -1, nil
+		if e := tt.set(); e != nil {
+			t.Errorf("%s: %v", tt.name, e)
+			continue
+		}
+		if r.code != tt.code || !bytes.Equal(r.param, tt.param) {
+			t.Errorf("%s set code %d with parameter %v, expected code %d with parameter %v", tt.name, r.code, r.param, tt.code, tt.param)
+		}
+	}
+}
+`)
+}
+
 func main() {
 	var err error
 
+	test := flag.Bool("test", false, "generate a test of the generated code instead")
+	flag.Parse()
+
 	v := Options{}
 
 	data, err := ioutil.ReadAll(os.Stdin)
@@ -155,6 +444,22 @@ func main() {
 		log.Fatal(err)
 	}
 
+	/* Hidden options are not part of the public API */
+	for i := range(v.Scope) {
+		var opts []Option
+		for _, opt := range(v.Scope[i].Option) {
+			if !opt.Hidden {
+				opts = append(opts, opt)
+			}
+		}
+		v.Scope[i].Option = opts
+	}
+
+	if *test {
+		writeTest(v)
+		return
+	}
+
 	fmt.Print(`// DO NOT EDIT THIS FILE BY HAND. This file was generated using
 // translate_fdb_options.go, part of the fdb-go repository, and a copy of the
 // fdb.options file (installed as part of the FoundationDB client, typically
@@ -182,22 +487,17 @@ func int64ToBytes(i int64) ([]byte, error) {
 
 	for _, scope := range(v.Scope) {
 		if strings.HasSuffix(scope.Name, "Option") {
-			receiver := scope.Name + "s"
-
 			for _, opt := range(scope.Option) {
-				if opt.Description != "Deprecated" { // Eww
-					writeOpt(receiver, opt)
-				}
+				writeOpt(scope, opt)
 			}
 			continue
 		}
 
 		if scope.Name == "MutationType" {
 			for _, opt := range(scope.Option) {
-				if opt.Description != "Deprecated" { // Eww
-					writeMutation(opt)
-				}
+				writeMutation(opt)
 			}
+			writeMutationVersions()
 			continue
 		}
 
@@ -207,11 +507,6 @@ func int64ToBytes(i int64) ([]byte, error) {
 			d = 1
 		}
 
-		// ConflictRangeType shouldn't be exported
-		if scope.Name == "ConflictRangeType" {
-			scope.Name = "conflictRangeType"
-		}
-
 		fmt.Printf(`
 type %s int
 const (
@@ -220,5 +515,10 @@ const (
 			writeEnum(scope, opt, d)
 		}
 		fmt.Println(")")
+
+		// ErrorPredicate values can test errors directly
+		if scope.Name == "ErrorPredicate" {
+			writeErrorPredicateTest(scope)
+		}
 	}
 }
@@ -0,0 +1,33 @@
+//go:build !fdb_api_610 && !fdb_api_620 && !fdb_api_630 && !fdb_api_700 && !fdb_api_710
+
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+// #cgo CFLAGS: -DFDB_API_VERSION=200
+import "C"
+
+// headerAPIVersion is the version of the FoundationDB C API header against
+// which the fdb package is built. Unless another version is selected with a
+// build tag (such as fdb_api_710), the fdb package is built against API version
+// 200.
+const headerAPIVersion = 200
@@ -0,0 +1,113 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package fdb
+
+// A DatabaseBackend provides the storage for a Database. The FoundationDB C
+// library provides the backend of each Database returned by Open, OpenDefault
+// or (Cluster).OpenDatabase; other implementations (for example, an in-memory
+// store for testing) may be used to construct a Database with NewDatabase.
+//
+// A Database constructed from any backend satisfies the Transactor and
+// ReadTransactor interfaces, so transactional functions (and the layers built
+// upon them, such as the subspace and directory packages) may be used with any
+// backend.
+type DatabaseBackend interface {
+	// CreateTransaction returns a new TransactionBackend for a transaction
+	// against this database.
+	CreateTransaction() (TransactionBackend, error)
+
+	// SetOption sets the database option identified by code (as listed in
+	// fdb.options) to the encoded parameter param. Backends may ignore options
+	// that are not meaningful to them.
+	SetOption(code int, param []byte) error
+}
+
+// A TransactionBackend provides the operations of a single transaction to a
+// Transaction (and its Snapshot). The semantics of each method match the
+// correspondingly-named method of Transaction; the snapshot parameter of read
+// methods indicates a snapshot read.
+//
+// Asynchronous results are returned as futures. Backends not implemented with
+// the FoundationDB C library may construct futures with NewFutureByteSlice,
+// NewFutureKey, NewFutureNil, NewFutureInt64, NewFutureStringSlice,
+// NewFutureKeyArray and NewFutureKeyValueArray.
+type TransactionBackend interface {
+	Get(key Key, snapshot bool) FutureByteSlice
+	GetKey(sel KeySelector, snapshot bool) FutureKey
+
+	// GetRange reads a single batch of the range between the begin and end
+	// key selectors. The iteration parameter counts the batches read so far
+	// by a RangeIterator (starting from 1), and may be used together with
+	// options.Mode to size the batch. The future reports whether more
+	// key-value pairs may remain in the range beyond the returned batch.
+	GetRange(begin, end KeySelector, options RangeOptions, snapshot bool, iteration int) FutureKeyValueArray
+
+	GetReadVersion() FutureInt64
+	SetReadVersion(version int64)
+	GetAddressesForKey(key Key) FutureStringSlice
+
+	// GetEstimatedRangeSizeBytes and GetApproximateSize were introduced in
+	// API versions 630 and 620 respectively. Backends that cannot provide an
+	// estimate may return a future that fails with ErrAPIVersionNotSupported.
+	GetEstimatedRangeSizeBytes(begin, end Key) FutureInt64
+	GetApproximateSize() FutureInt64
+
+	// GetRangeSplitPoints was introduced in API version 700, and may likewise
+	// return a future that fails with ErrAPIVersionNotSupported.
+	GetRangeSplitPoints(begin, end Key, chunkSize int64) FutureKeyArray
+
+	Set(key Key, value []byte)
+	Clear(key Key)
+	ClearRange(begin, end Key)
+
+	// AtomicOp applies the mutation identified by code (as listed in the
+	// MutationType scope of fdb.options) to key with parameter param.
+	AtomicOp(key Key, param []byte, code int)
+
+	// AddConflictRange adds the range between begin and end to the read (or,
+	// if write is true, the write) conflict ranges of the transaction.
+	AddConflictRange(begin, end Key, write bool) error
+
+	Watch(key Key) FutureNil
+	Commit() FutureNil
+
+	// GetVersionstamp returns a future that becomes ready with the 10-byte
+	// versionstamp of the transaction once it has been committed. It was
+	// introduced in API version 410.
+	GetVersionstamp() FutureKey
+
+	GetCommittedVersion() (int64, error)
+	OnError(e Error) FutureNil
+	Reset()
+	Cancel()
+
+	// SetOption sets the transaction option identified by code (as listed in
+	// fdb.options) to the encoded parameter param. Backends may ignore options
+	// that are not meaningful to them.
+	SetOption(code int, param []byte) error
+}
+
+// NewDatabase returns a Database whose transactions are provided by the given
+// backend. It is not necessary to call NewDatabase to use the FoundationDB C
+// library; use Open or OpenDefault instead.
+func NewDatabase(backend DatabaseBackend) Database {
+	return Database{database: &database{backend}}
+}
@@ -1,3 +1,5 @@
+//go:build !fdb_api_610 && !fdb_api_620 && !fdb_api_630 && !fdb_api_700 && !fdb_api_710
+
 // FoundationDB Go API
 // Copyright (c) 2013 FoundationDB, LLC
 
@@ -22,15 +24,20 @@
 package fdb
 
 /*
- #define FDB_API_VERSION 200
  #include <foundationdb/fdb_c.h>
+ #include <stdlib.h>
 */
 import "C"
 
 import (
 	"runtime"
+	"unsafe"
 )
 
+// Before API version 610, the FoundationDB C API opens a database through a
+// cluster handle. When the fdb package is built against a newer header, the
+// Cluster type is retained only for compatibility (see create_database.go).
+
 // Cluster is a handle to a FoundationDB cluster. Cluster is a lightweight
 // object that may be efficiently copied, and is safe for concurrent use by
 // multiple goroutines.
@@ -66,8 +73,65 @@ func (c Cluster) OpenDatabase(dbName []byte) (Database, error) {
 
 	C.fdb_future_destroy(f)
 
-	d := &database{outd}
-	runtime.SetFinalizer(d, (*database).destroy)
+	return NewDatabase(newCDatabase(outd)), nil
+}
+
+var openClusters = make(map[string]Cluster)
+
+// openDatabase opens the named database from the cluster identified by the
+// cluster file, creating (and caching) the cluster handle if necessary.
+func openDatabase(clusterFile string, dbName []byte) (Database, error) {
+	cluster, ok := openClusters[clusterFile]
+	if !ok {
+		var e error
+		cluster, e = createCluster(clusterFile)
+		if e != nil {
+			return Database{}, e
+		}
+		openClusters[clusterFile] = cluster
+	}
+
+	return cluster.OpenDatabase(dbName)
+}
+
+func createCluster(clusterFile string) (Cluster, error) {
+	var cf *C.char
+
+	if len(clusterFile) != 0 {
+		cf = C.CString(clusterFile)
+		defer C.free(unsafe.Pointer(cf))
+	}
+
+	f := C.fdb_create_cluster(cf)
+	fdb_future_block_until_ready(f)
+
+	var outc *C.FDBCluster
+
+	if err := C.fdb_future_get_cluster(f, &outc); err != 0 {
+		return Cluster{}, Error{int(err)}
+	}
+
+	C.fdb_future_destroy(f)
+
+	c := &cluster{outc}
+	runtime.SetFinalizer(c, (*cluster).destroy)
+
+	return Cluster{c}, nil
+}
+
+// CreateCluster returns a cluster handle to the FoundationDB cluster identified
+// by the provided cluster file.
+func CreateCluster(clusterFile string) (Cluster, error) {
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
+
+	if apiVersion == 0 {
+		return Cluster{}, ErrAPIVersionUnset
+	}
+
+	if !networkStarted {
+		return Cluster{}, ErrNetworkNotSetup
+	}
 
-	return Database{d}, nil
+	return createCluster(clusterFile)
 }
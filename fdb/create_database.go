@@ -0,0 +1,89 @@
+//go:build fdb_api_610 || fdb_api_620 || fdb_api_630 || fdb_api_700 || fdb_api_710
+
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+/*
+ #include <foundationdb/fdb_c.h>
+ #include <stdlib.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// Beginning with API version 610, the FoundationDB C API opens a database
+// directly with fdb_create_database, and cluster handles no longer exist.
+
+var errClusterRemoved = fmt.Errorf("cluster handles are not supported by FoundationDB API version %d (use Open)", headerAPIVersion)
+
+// Cluster is a handle to a FoundationDB cluster. Cluster handles were removed
+// from the FoundationDB C API in API version 610, and when the fdb package is
+// built against such a version a Cluster cannot be created.
+//
+// Deprecated: Use Open or OpenDefault to obtain a database handle directly.
+type Cluster struct {
+}
+
+// OpenDatabase returns a database handle from the FoundationDB cluster. When
+// the fdb package is built against API version 610 or later, OpenDatabase
+// always returns an error.
+//
+// Deprecated: Use Open or OpenDefault to obtain a database handle directly.
+func (c Cluster) OpenDatabase(dbName []byte) (Database, error) {
+	return Database{}, errClusterRemoved
+}
+
+// CreateCluster returns a cluster handle to the FoundationDB cluster identified
+// by the provided cluster file. When the fdb package is built against API
+// version 610 or later, CreateCluster always returns an error.
+//
+// Deprecated: Use Open or OpenDefault to obtain a database handle directly.
+func CreateCluster(clusterFile string) (Cluster, error) {
+	return Cluster{}, errClusterRemoved
+}
+
+// openDatabase opens the database from the cluster identified by the cluster
+// file. Since API version 610, the only database name is []byte("DB").
+func openDatabase(clusterFile string, dbName []byte) (Database, error) {
+	if string(dbName) != "DB" {
+		return Database{}, ErrInvalidDatabaseName
+	}
+
+	var cf *C.char
+
+	if len(clusterFile) != 0 {
+		cf = C.CString(clusterFile)
+		defer C.free(unsafe.Pointer(cf))
+	}
+
+	var outd *C.FDBDatabase
+
+	if err := C.fdb_create_database(cf, &outd); err != 0 {
+		return Database{}, Error{int(err)}
+	}
+
+	return NewDatabase(newCDatabase(outd)), nil
+}
@@ -21,14 +21,12 @@
 
 package fdb
 
-/*
- #define FDB_API_VERSION 200
- #include <foundationdb/fdb_c.h>
-*/
-import "C"
-
 import (
-	"runtime"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"time"
 )
 
 // Database is a handle to a FoundationDB database. Database is a lightweight
@@ -41,10 +39,11 @@ import (
 // method.
 type Database struct {
 	*database
+	retryPolicy *RetryPolicy
 }
 
 type database struct {
-	ptr *C.FDBDatabase
+	backend DatabaseBackend
 }
 
 // DatabaseOptions is a handle with which to set options that affect a Database
@@ -55,13 +54,7 @@ type DatabaseOptions struct {
 }
 
 func (opt DatabaseOptions) setOpt(code int, param []byte) error {
-	return setOpt(func(p *C.uint8_t, pl C.int) C.fdb_error_t {
-		return C.fdb_database_set_option(opt.d.ptr, C.FDBDatabaseOption(code), p, pl)
-	}, param)
-}
-
-func (d *database) destroy() {
-	C.fdb_database_destroy(d.ptr)
+	return opt.d.backend.SetOption(code, param)
 }
 
 // CreateTransaction returns a new FoundationDB transaction. It is generally
@@ -69,38 +62,37 @@ func (d *database) destroy() {
 // automatically creating and committing a transaction with appropriate retry
 // behavior.
 func (d Database) CreateTransaction() (Transaction, error) {
-	var outt *C.FDBTransaction
+	tb, e := d.backend.CreateTransaction()
+	if e != nil {
+		return Transaction{}, e
+	}
 
-	if err := C.fdb_database_create_transaction(d.ptr, &outt); err != 0 {
-		return Transaction{}, Error{int(err)}
+	return Transaction{&transaction{backend: tb, db: d}}, nil
+}
+
+func (d Database) transact(ctx context.Context, f func(Transaction) (interface{}, error)) (interface{}, error) {
+	tr, e := d.CreateTransaction()
+	/* Any error here is non-retryable */
+	if e != nil {
+		return nil, e
 	}
 
-	t := &transaction{outt, d}
-	runtime.SetFinalizer(t, (*transaction).destroy)
+	stop := context.AfterFunc(ctx, tr.Cancel)
+	defer stop()
 
-	return Transaction{t}, nil
-}
+	wrapped := func() (ret interface{}, e error) {
+		defer panicToError(&e)
 
-func retryable(wrapped func() (interface{}, error), onError func(Error) FutureNil) (ret interface{}, e error) {
-	for {
-		ret, e = wrapped()
+		ret, e = f(tr)
 
-		/* No error means success! */
 		if e == nil {
-			return
-		}
-
-		ep, ok := e.(Error)
-		if ok {
-			e = onError(ep).Get()
+			e = tr.Commit().Get()
 		}
 
-		/* If OnError returns an error, then it's not
-		/* retryable; otherwise take another pass at things */
-		if e != nil {
-			return
-		}
+		return
 	}
+
+	return retryable(ctx, d.policy(), tr, wrapped)
 }
 
 // Transact runs a caller-provided function inside a retry loop, providing it
@@ -123,25 +115,17 @@ func retryable(wrapped func() (interface{}, error), onError func(Error) FutureNi
 // See the Transactor interface for an example of using Transact with
 // Transaction and Database objects.
 func (d Database) Transact(f func(Transaction) (interface{}, error)) (interface{}, error) {
-	tr, e := d.CreateTransaction()
-	/* Any error here is non-retryable */
-	if e != nil {
-		return nil, e
-	}
-
-	wrapped := func() (ret interface{}, e error) {
-		defer panicToError(&e)
-
-		ret, e = f(tr)
-
-		if e == nil {
-			e = tr.Commit().Get()
-		}
-
-		return
-	}
+	return d.transact(context.Background(), f)
+}
 
-	return retryable(wrapped, tr.OnError)
+// TransactContext is like Transact, but stops retrying once ctx is done. When
+// ctx is cancelled or its deadline passes, the Transaction is cancelled (see
+// (Transaction).Cancel), causing any outstanding reads and commit to fail, and
+// TransactContext returns ctx.Err() wrapped together with the last error
+// returned or panicked by the function. Both errors may be inspected with
+// errors.Is and errors.As.
+func (d Database) TransactContext(ctx context.Context, f func(Transaction) (interface{}, error)) (interface{}, error) {
+	return d.transact(ctx, f)
 }
 
 // ReadTransact runs a caller-provided function inside a retry loop, providing
@@ -163,25 +147,154 @@ func (d Database) Transact(f func(Transaction) (interface{}, error)) (interface{
 // See the ReadTransactor interface for an example of using ReadTransact with
 // Transaction, Snapshot and Database objects.
 func (d Database) ReadTransact(f func(ReadTransaction) (interface{}, error)) (interface{}, error) {
-	tr, e := d.CreateTransaction()
-	/* Any error here is non-retryable */
-	if e != nil {
+	return d.transact(context.Background(), func(tr Transaction) (interface{}, error) {
+		return f(tr)
+	})
+}
+
+// ReadTransactContext is like ReadTransact, but stops retrying once ctx is
+// done. See (Database).TransactContext for details of how the Transaction is
+// cancelled and the error that is returned.
+func (d Database) ReadTransactContext(ctx context.Context, f func(ReadTransaction) (interface{}, error)) (interface{}, error) {
+	return d.transact(ctx, func(tr Transaction) (interface{}, error) {
+		return f(tr)
+	})
+}
+
+// ErrInvalidMarker is the error returned by TransactIdempotent when the marker
+// key of a transaction holds a value that it did not write, as may happen if
+// markerPrefix overlaps keys written by other code.
+var ErrInvalidMarker = errors.New("idempotence marker key holds an unexpected value")
+
+// TransactIdempotent is like Transact, but ensures that the writes of the
+// caller-provided function are committed at most once, even if a commit fails
+// with an error for which IsMaybeCommitted is true (such as
+// ErrCommitUnknownResult). This makes it safe to use non-idempotent operations,
+// such as (Transaction).Add, in the function.
+//
+// Each commit includes a write of a marker key, formed by appending the current
+// time and a unique transaction ID to markerPrefix. After a commit with an
+// unknown result, each retry first reads the marker key; if the marker is
+// present, the earlier commit succeeded and TransactIdempotent returns the
+// value returned by the function in that attempt without calling it again. If
+// the marker holds a value that TransactIdempotent did not write, it returns
+// ErrInvalidMarker.
+//
+// Once TransactIdempotent succeeds, the marker key is cleared in a separate
+// transaction. Clearing the marker is best effort: if it fails, the error is
+// not reported and the marker is left behind. markerPrefix should therefore
+// identify a region of keys reserved for markers, from which markers left
+// behind may be removed with ClearIdempotenceMarkers.
+func (d Database) TransactIdempotent(markerPrefix KeyConvertible, f func(Transaction) (interface{}, error)) (interface{}, error) {
+	id := make([]byte, 16)
+	if _, e := rand.Read(id); e != nil {
 		return nil, e
 	}
+	marker := append(markerTime(markerPrefix, time.Now()), id...)
 
-	wrapped := func() (ret interface{}, e error) {
-		defer panicToError(&e)
+	policy := d.policy()
 
-		ret, e = f(tr)
+	var maybeCommitted bool
+	onRetry := policy.OnRetry
+	policy.OnRetry = func(attempt int, e error) {
+		if IsMaybeCommitted(e) {
+			maybeCommitted = true
+		}
+		if onRetry != nil {
+			onRetry(attempt, e)
+		}
+	}
 
-		if e == nil {
-			e = tr.Commit().Get()
+	/* The marker records which attempt committed, so that the value
+	/* returned by the function in that attempt can be returned */
+	var attempt int
+	results := make(map[uint64]interface{})
+
+	ret, e := d.WithRetryPolicy(policy).Transact(func(tr Transaction) (interface{}, error) {
+		if maybeCommitted {
+			v, e := tr.Get(marker).Get()
+			if e != nil {
+				return nil, e
+			}
+			if v != nil {
+				if len(v) != 8 {
+					return nil, ErrInvalidMarker
+				}
+				r, ok := results[binary.LittleEndian.Uint64(v)]
+				if !ok {
+					return nil, ErrInvalidMarker
+				}
+				return r, nil
+			}
 		}
 
-		return
+		attempt += 1
+
+		r, e := f(tr)
+		if e != nil {
+			return nil, e
+		}
+
+		results[uint64(attempt)] = r
+
+		b, e := int64ToBytes(int64(attempt))
+		if e != nil {
+			return nil, e
+		}
+		tr.Set(marker, b)
+
+		return r, nil
+	})
+
+	if e == nil {
+		d.Transact(func(tr Transaction) (interface{}, error) {
+			tr.Clear(marker)
+			return nil, nil
+		})
 	}
 
-	return retryable(wrapped, tr.OnError)
+	return ret, e
+}
+
+// ClearIdempotenceMarkers clears the marker keys under markerPrefix left behind
+// by calls to TransactIdempotent that began before the provided time. A marker
+// must not be cleared while the call that wrote it may still be retrying, so
+// before should leave a generous margin for the duration of transactions and
+// for the skew between the clocks of clients (for example, an hour ago).
+func (d Database) ClearIdempotenceMarkers(markerPrefix KeyConvertible, before time.Time) error {
+	_, e := d.Transact(func(tr Transaction) (interface{}, error) {
+		tr.ClearRange(KeyRange{markerPrefix.FDBKey(), markerTime(markerPrefix, before)})
+		return nil, nil
+	})
+	return e
+}
+
+// markerTime returns markerPrefix followed by t, encoded so that the markers of
+// TransactIdempotent sort by the time at which they were written.
+func markerTime(markerPrefix KeyConvertible, t time.Time) Key {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(t.UnixNano()))
+	return append(append(Key{}, markerPrefix.FDBKey()...), b[:]...)
+}
+
+// WithRetryPolicy returns a copy of the Database handle whose Transact,
+// TransactContext, ReadTransact and ReadTransactContext methods retry according
+// to the provided RetryPolicy. The receiver (and any other handle to the same
+// database) is unaffected, so a policy may also be applied to a single call:
+//
+//     db.WithRetryPolicy(fdb.RetryPolicy{MaxAttempts: 3}).Transact(f)
+func (d Database) WithRetryPolicy(p RetryPolicy) Database {
+	d.retryPolicy = &p
+	return d
+}
+
+// policy returns the RetryPolicy of d, or the zero RetryPolicy if none has been
+// set with WithRetryPolicy.
+func (d Database) policy() RetryPolicy {
+	if d.retryPolicy == nil {
+		return RetryPolicy{}
+	}
+	return *d.retryPolicy
 }
 
 // Options returns a DatabaseOptions instance suitable for setting options
@@ -211,6 +324,12 @@ func (d Database) LocalityGetBoundaryKeys(er ExactRange, limit int, readVersion
 		tr.SetReadVersion(readVersion)
 	}
 
+	return localityGetBoundaryKeys(tr, er, limit)
+}
+
+// localityGetBoundaryKeys reads the boundary keys within the provided range in
+// tr, as described by LocalityGetBoundaryKeys.
+func localityGetBoundaryKeys(tr Transaction, er ExactRange, limit int) ([]Key, error) {
 	tr.Options().SetAccessSystemKeys()
 
 	bk, ek := er.FDBRangeKeys()
@@ -0,0 +1,422 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package fdb_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+func TestTransactContextCancelled(t *testing.T) {
+	db := memdb.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int
+	_, e := db.TransactContext(ctx, func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		if calls == 3 {
+			cancel()
+		}
+		return nil, fdb.ErrNotCommitted
+	})
+
+	if calls != 3 {
+		t.Errorf("function called %d times, expected 3", calls)
+	}
+	if !errors.Is(e, context.Canceled) {
+		t.Errorf("error %v is not context.Canceled", e)
+	}
+	if !errors.Is(e, fdb.ErrNotCommitted) {
+		t.Errorf("error %v is not the last error of the function", e)
+	}
+}
+
+func TestTransactContextDeadline(t *testing.T) {
+	db := memdb.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, e := db.ReadTransactContext(ctx, func(rtr fdb.ReadTransaction) (interface{}, error) {
+		time.Sleep(time.Millisecond)
+		return nil, fdb.ErrTransactionTooOld
+	})
+
+	if !errors.Is(e, context.DeadlineExceeded) {
+		t.Errorf("error %v is not context.DeadlineExceeded", e)
+	}
+	if !errors.Is(e, fdb.ErrTransactionTooOld) {
+		t.Errorf("error %v is not the last error of the function", e)
+	}
+}
+
+func TestTransactContextDone(t *testing.T) {
+	db := memdb.New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		transact func(f func()) error
+	}{
+		{"TransactContext", func(f func()) error {
+			_, e := db.TransactContext(ctx, func(tr fdb.Transaction) (interface{}, error) {
+				f()
+				return nil, nil
+			})
+			return e
+		}},
+		{"ReadTransactContext", func(f func()) error {
+			_, e := db.ReadTransactContext(ctx, func(rtr fdb.ReadTransaction) (interface{}, error) {
+				f()
+				return nil, nil
+			})
+			return e
+		}},
+	}
+
+	for _, tt := range tests {
+		var called bool
+		e := tt.transact(func() { called = true })
+		if called {
+			t.Errorf("%s called the function with a cancelled context", tt.name)
+		}
+		if e != context.Canceled {
+			t.Errorf("%s returned %v, expected context.Canceled", tt.name, e)
+		}
+	}
+}
+
+func TestTransactIdempotent(t *testing.T) {
+	db := memdb.New()
+
+	var retries, total int
+	var calledSinceRetry bool
+	faulty := db.WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.3}).WithRetryPolicy(fdb.RetryPolicy{
+		OnRetry: func(attempt int, e error) {
+			retries++
+			calledSinceRetry = false
+		},
+	})
+
+	markers := fdb.Key("markers/")
+	counter := fdb.Key("counter")
+
+	/* Counts the calls whose effect was committed by an attempt reporting
+	/* ErrCommitUnknownResult, and so were not called again */
+	var recovered int
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		retries = 0
+		_, e := faulty.TransactIdempotent(markers, func(tr fdb.Transaction) (interface{}, error) {
+			calledSinceRetry = true
+			tr.Add(counter, []byte{1, 0, 0, 0})
+			return nil, nil
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if retries > 0 && !calledSinceRetry {
+			recovered++
+		}
+		total += retries
+	}
+
+	if recovered == 0 {
+		t.Fatalf("no commit with an unknown result was applied in %d transactions (%d retries)", n, total)
+	}
+
+	ret, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+		v, e := rtr.Get(counter).Get()
+		if e != nil {
+			return nil, e
+		}
+		kvs, e := rtr.GetRange(fdb.KeyRange{Begin: markers, End: fdb.Key("markers0")}, fdb.RangeOptions{}).GetSliceWithError()
+		if e != nil {
+			return nil, e
+		}
+		return []interface{}{v, kvs}, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	v := ret.([]interface{})[0].([]byte)
+	if c := binary.LittleEndian.Uint32(v); c != n {
+		t.Errorf("counter is %d after %d transactions (%d recovered from an unknown result)", c, n, recovered)
+	}
+	if kvs := ret.([]interface{})[1].([]fdb.KeyValue); len(kvs) != 0 {
+		t.Errorf("%d marker keys left behind", len(kvs))
+	}
+}
+
+// countMarkers returns the number of marker keys under markers.
+func countMarkers(t *testing.T, db fdb.Database, markers fdb.Key) int {
+	r, e := fdb.PrefixRange(markers)
+	if e != nil {
+		t.Fatal(e)
+	}
+	kvs, e := fdb.ReadTransact(db, func(rtr fdb.ReadTransaction) ([]fdb.KeyValue, error) {
+		return rtr.GetRange(r, fdb.RangeOptions{}).GetSliceWithError()
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	return len(kvs)
+}
+
+func TestTransactIdempotentInvalidMarker(t *testing.T) {
+	markers := fdb.Key("markers/")
+	r, _ := fdb.PrefixRange(markers)
+
+	for _, bad := range [][]byte{{1, 2, 3}, {99, 0, 0, 0, 0, 0, 0, 0}} {
+		db := memdb.New()
+
+		/* Overwrites any marker committed by an attempt reporting an
+		/* unknown result, before it is read by the next attempt */
+		overwrite := func(int, error) {
+			db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+				kvs, e := tr.GetRange(r, fdb.RangeOptions{}).GetSliceWithError()
+				for _, kv := range kvs {
+					tr.Set(kv.Key, bad)
+				}
+				return nil, e
+			})
+		}
+		faulty := db.WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.5}).WithRetryPolicy(fdb.RetryPolicy{OnRetry: overwrite})
+
+		var invalid int
+		for i := 0; i < 100; i++ {
+			_, e := faulty.TransactIdempotent(markers, func(tr fdb.Transaction) (interface{}, error) {
+				return nil, nil
+			})
+			switch e {
+			case nil:
+			case fdb.ErrInvalidMarker:
+				invalid++
+			default:
+				t.Fatalf("marker % x: returned %v", bad, e)
+			}
+		}
+		if invalid == 0 {
+			t.Errorf("marker % x: never returned ErrInvalidMarker", bad)
+		}
+	}
+}
+
+func TestClearIdempotenceMarkers(t *testing.T) {
+	db := memdb.New()
+	markers := fdb.Key("markers/")
+
+	/* With a single attempt, many markers are left behind by failed
+	/* commits or failed clears */
+	faulty := db.WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.5}).WithRetryPolicy(fdb.RetryPolicy{MaxAttempts: 1})
+	leave := func() int {
+		before := countMarkers(t, db, markers)
+		for i := 0; i < 50; i++ {
+			faulty.TransactIdempotent(markers, func(tr fdb.Transaction) (interface{}, error) {
+				tr.Add(fdb.Key("counter"), []byte{1})
+				return nil, nil
+			})
+		}
+		return countMarkers(t, db, markers) - before
+	}
+
+	old := leave()
+	time.Sleep(time.Millisecond)
+	cutoff := time.Now()
+	recent := leave()
+	if old == 0 || recent == 0 {
+		t.Fatalf("left %d and %d markers behind, expected some of each", old, recent)
+	}
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Set(fdb.Key("markers0"), []byte("other"))
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if e := db.ClearIdempotenceMarkers(markers, cutoff); e != nil {
+		t.Fatal(e)
+	}
+	if n := countMarkers(t, db, markers); n != recent {
+		t.Errorf("%d markers left after clearing those before the cutoff, expected %d", n, recent)
+	}
+
+	if e := db.ClearIdempotenceMarkers(markers, time.Now()); e != nil {
+		t.Fatal(e)
+	}
+	if n := countMarkers(t, db, markers); n != 0 {
+		t.Errorf("%d markers left after clearing all", n)
+	}
+	v, e := fdb.ReadTransact(db, func(rtr fdb.ReadTransaction) ([]byte, error) {
+		return rtr.Get(fdb.Key("markers0")).Get()
+	})
+	if e != nil || string(v) != "other" {
+		t.Errorf("key following the markers holds %q (%v), expected it to be kept", v, e)
+	}
+}
+
+type account struct {
+	name string
+	balance int
+}
+
+func TestGenericTransact(t *testing.T) {
+	db := memdb.New()
+
+	a, e := fdb.Transact(db, func(tr fdb.Transaction) (account, error) {
+		tr.Set(fdb.Key("alice"), []byte{10})
+		return account{"alice", 10}, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if a != (account{"alice", 10}) {
+		t.Errorf("Transact returned %v, expected {alice 10}", a)
+	}
+
+	v, e := fdb.ReadTransact(db, func(rtr fdb.ReadTransaction) ([]byte, error) {
+		return rtr.Get(fdb.Key("alice")).Get()
+	})
+	if e != nil || len(v) != 1 || v[0] != 10 {
+		t.Errorf("ReadTransact returned (%v, %v), expected ([10], nil)", v, e)
+	}
+
+	/* A Transaction is also a Transactor */
+	n, e := fdb.Transact(db, func(tr fdb.Transaction) (int, error) {
+		return fdb.Transact(tr, func(tr fdb.Transaction) (int, error) {
+			return 42, nil
+		})
+	})
+	if n != 42 || e != nil {
+		t.Errorf("nested Transact returned (%d, %v), expected (42, nil)", n, e)
+	}
+
+	/* A nil result of an interface type is its zero value */
+	var err error
+	err, e = fdb.Transact(db, func(tr fdb.Transaction) (error, error) {
+		return nil, nil
+	})
+	if err != nil || e != nil {
+		t.Errorf("Transact returned (%v, %v), expected (nil, nil)", err, e)
+	}
+}
+
+// strayTransactor is a Transactor returning v in place of the result of
+// every transactional function.
+type strayTransactor struct {
+	v interface{}
+}
+
+func (st strayTransactor) Transact(func(fdb.Transaction) (interface{}, error)) (interface{}, error) {
+	return st.v, nil
+}
+
+func (st strayTransactor) ReadTransact(func(fdb.ReadTransaction) (interface{}, error)) (interface{}, error) {
+	return st.v, nil
+}
+
+func TestGenericTransactMismatch(t *testing.T) {
+	st := strayTransactor{"42"}
+
+	n, e := fdb.Transact(st, func(tr fdb.Transaction) (int, error) { return 42, nil })
+	if n != 0 || e == nil || e.Error() != "transactional function returned string, expected int" {
+		t.Errorf("Transact returned (%d, %v), expected a type mismatch", n, e)
+	}
+	err, e := fdb.ReadTransact(st, func(rtr fdb.ReadTransaction) (error, error) { return nil, nil })
+	if err != nil || e == nil || e.Error() != "transactional function returned string, expected error" {
+		t.Errorf("ReadTransact returned (%v, %v), expected a type mismatch", err, e)
+	}
+
+	/* A nil result is the zero value of any type */
+	if n, e := fdb.Transact(strayTransactor{}, func(tr fdb.Transaction) (int, error) { return 42, nil }); n != 0 || e != nil {
+		t.Errorf("Transact of a nil result returned (%d, %v), expected (0, nil)", n, e)
+	}
+}
+
+func TestGenericTransactError(t *testing.T) {
+	db := memdb.New()
+
+	tests := []struct {
+		name string
+		f func(tr fdb.Transaction) (int, error)
+		err error
+	}{
+		{"returned", func(tr fdb.Transaction) (int, error) { return 42, errTemporary }, errTemporary},
+		{"returned Error", func(tr fdb.Transaction) (int, error) { return 42, fdb.ErrInvertedRange }, fdb.ErrInvertedRange},
+		{"panicked Error", func(tr fdb.Transaction) (int, error) { panic(fdb.ErrInvertedRange) }, fdb.ErrInvertedRange},
+		{"MustGet", func(tr fdb.Transaction) (int, error) {
+			tr.Cancel()
+			return len(tr.Get(fdb.Key("key")).MustGet()), nil
+		}, fdb.ErrTransactionCancelled},
+	}
+
+	for _, tt := range tests {
+		n, e := fdb.Transact(db, tt.f)
+		if n != 0 || !errors.Is(e, tt.err) {
+			t.Errorf("Transact with %s error returned (%d, %v), expected (0, %v)", tt.name, n, e, tt.err)
+		}
+
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+		n, e = fdb.Transact(tr, tt.f)
+		if n != 0 || !errors.Is(e, tt.err) {
+			t.Errorf("Transact of a Transaction with %s error returned (%d, %v), expected (0, %v)", tt.name, n, e, tt.err)
+		}
+	}
+
+	s, e := fdb.ReadTransact(db, func(rtr fdb.ReadTransaction) (string, error) {
+		panic(fdb.ErrInvertedRange)
+	})
+	if s != "" || !errors.Is(e, fdb.ErrInvertedRange) {
+		t.Errorf("ReadTransact with panicked Error returned (%q, %v), expected (\"\", ErrInvertedRange)", s, e)
+	}
+
+	/* A panicked retryable Error is retried */
+	var calls int
+	n, e := fdb.Transact(db, func(tr fdb.Transaction) (int, error) {
+		calls++
+		if calls == 1 {
+			panic(fdb.ErrNotCommitted)
+		}
+		return calls, nil
+	})
+	if n != 2 || e != nil {
+		t.Errorf("Transact retrying a panicked Error returned (%d, %v), expected (2, nil)", n, e)
+	}
+}
@@ -119,7 +119,7 @@ func (dl directoryLayer) createOrOpen(rtr fdb.ReadTransaction, tr *fdb.Transacti
 	if prefix == nil {
 		newss, e := dl.allocator.allocate(*tr, dl.contentSS)
 		if e != nil {
-			return nil, fmt.Errorf("unable to allocate new directory prefix (%s)", e.Error())
+			return nil, fmt.Errorf("unable to allocate new directory prefix (%w)", e)
 		}
 
 		if !isRangeEmpty(rtr, newss) {
@@ -170,50 +170,34 @@ func (dl directoryLayer) createOrOpen(rtr fdb.ReadTransaction, tr *fdb.Transacti
 }
 
 func (dl directoryLayer) CreateOrOpen(t fdb.Transactor, path []string, layer []byte) (DirectorySubspace, error) {
-	r, e := t.Transact(func (tr fdb.Transaction) (interface{}, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
 		return dl.createOrOpen(tr, &tr, path, layer, nil, true, true)
 	})
-	if e != nil {
-		return nil, e
-	}
-	return r.(DirectorySubspace), nil
 }
 
 func (dl directoryLayer) Create(t fdb.Transactor, path []string, layer []byte) (DirectorySubspace, error) {
-	r, e := t.Transact(func (tr fdb.Transaction) (interface{}, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
 		return dl.createOrOpen(tr, &tr, path, layer, nil, true, false)
 	})
-	if e != nil {
-		return nil, e
-	}
-	return r.(DirectorySubspace), nil
 }
 
 func (dl directoryLayer) CreatePrefix(t fdb.Transactor, path []string, layer []byte, prefix []byte) (DirectorySubspace, error) {
 	if prefix == nil {
 		prefix = []byte{}
 	}
-	r, e := t.Transact(func (tr fdb.Transaction) (interface{}, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
 		return dl.createOrOpen(tr, &tr, path, layer, prefix, true, false)
 	})
-	if e != nil {
-		return nil, e
-	}
-	return r.(DirectorySubspace), nil
 }
 
 func (dl directoryLayer) Open(rt fdb.ReadTransactor, path []string, layer []byte) (DirectorySubspace, error) {
-	r, e := rt.ReadTransact(func (rtr fdb.ReadTransaction) (interface{}, error) {
+	return fdb.ReadTransact(rt, func (rtr fdb.ReadTransaction) (DirectorySubspace, error) {
 		return dl.createOrOpen(rtr, nil, path, layer, nil, false, true)
 	})
-	if e != nil {
-		return nil, e
-	}
-	return r.(DirectorySubspace), nil
 }
 
 func (dl directoryLayer) Exists(rt fdb.ReadTransactor, path []string) (bool, error) {
-	r, e := rt.ReadTransact(func (rtr fdb.ReadTransaction) (interface{}, error) {
+	return fdb.ReadTransact(rt, func (rtr fdb.ReadTransaction) (bool, error) {
 		if e := dl.checkVersion(rtr, nil); e != nil {
 			return false, e
 		}
@@ -233,14 +217,10 @@ func (dl directoryLayer) Exists(rt fdb.ReadTransactor, path []string) (bool, err
 
 		return true, nil
 	})
-	if e != nil {
-		return false, e
-	}
-	return r.(bool), nil
 }
 
 func (dl directoryLayer) List(rt fdb.ReadTransactor, path []string) ([]string, error) {
-	r, e := rt.ReadTransact(func (rtr fdb.ReadTransaction) (interface{}, error) {
+	return fdb.ReadTransact(rt, func (rtr fdb.ReadTransaction) ([]string, error) {
 		if e := dl.checkVersion(rtr, nil); e != nil {
 			return nil, e
 		}
@@ -260,10 +240,6 @@ func (dl directoryLayer) List(rt fdb.ReadTransactor, path []string) ([]string, e
 
 		return dl.subdirNames(rtr, node.subspace)
 	})
-	if e != nil {
-		return nil, e
-	}
-	return r.([]string), nil
 }
 
 func (dl directoryLayer) MoveTo(t fdb.Transactor, newAbsolutePath []string) (DirectorySubspace, error) {
@@ -271,7 +247,7 @@ func (dl directoryLayer) MoveTo(t fdb.Transactor, newAbsolutePath []string) (Dir
 }
 
 func (dl directoryLayer) Move(t fdb.Transactor, oldPath []string, newPath []string) (DirectorySubspace, error) {
-	r, e := t.Transact(func (tr fdb.Transaction) (interface{}, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (DirectorySubspace, error) {
 		if e := dl.checkVersion(tr, &tr); e != nil {
 			return nil, e
 		}
@@ -322,14 +298,10 @@ func (dl directoryLayer) Move(t fdb.Transactor, oldPath []string, newPath []stri
 
 		return dl.contentsOfNode(oldNode.subspace, newPath, oldNode._layer.MustGet())
 	})
-	if e != nil {
-		return nil, e
-	}
-	return r.(DirectorySubspace), nil
 }
 
 func (dl directoryLayer) Remove(t fdb.Transactor, path []string) (bool, error) {
-	r, e := t.Transact(func (tr fdb.Transaction) (interface{}, error) {
+	return fdb.Transact(t, func (tr fdb.Transaction) (bool, error) {
 		if e := dl.checkVersion(tr, &tr); e != nil {
 			return false, e
 		}
@@ -359,10 +331,6 @@ func (dl directoryLayer) Remove(t fdb.Transactor, path []string) (bool, error) {
 
 		return true, nil
 	})
-	if e != nil {
-		return false, e
-	}
-	return r.(bool), nil
 }
 
 func (dl directoryLayer) removeRecursive(tr fdb.Transaction, node subspace.Subspace) error {
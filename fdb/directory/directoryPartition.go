@@ -22,6 +22,8 @@
 package directory
 
 import (
+	"errors"
+
 	"github.com/FoundationDB/fdb-go/fdb"
 	"github.com/FoundationDB/fdb-go/fdb/subspace"
 	"github.com/FoundationDB/fdb-go/fdb/tuple"
@@ -36,6 +38,10 @@ func (dp directoryPartition) Sub(el ...tuple.TupleElement) subspace.Subspace {
 	panic("cannot open subspace in the root of a directory partition")
 }
 
+func (dp directoryPartition) SubE(el ...tuple.TupleElement) (subspace.Subspace, error) {
+	return nil, errors.New("cannot open subspace in the root of a directory partition")
+}
+
 func (dp directoryPartition) Bytes() []byte {
 	panic("cannot get key for the root of a directory partition")
 }
@@ -44,6 +50,10 @@ func (dp directoryPartition) Pack(t tuple.Tuple) fdb.Key {
 	panic("cannot pack keys using the root of a directory partition")
 }
 
+func (dp directoryPartition) PackE(t tuple.Tuple) (fdb.Key, error) {
+	return nil, errors.New("cannot pack keys using the root of a directory partition")
+}
+
 func (dp directoryPartition) Unpack(k fdb.KeyConvertible) (tuple.Tuple, error) {
 	panic("cannot unpack keys using the root of a directory partition")
 }
@@ -0,0 +1,93 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package directory_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/FoundationDB/fdb-go/fdb/directory"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// panics returns true if f panics.
+func panics(f func()) (panicked bool) {
+	defer func() {
+		if recover() != nil {
+			panicked = true
+		}
+	}()
+	f()
+	return false
+}
+
+func TestPartitionPackE(t *testing.T) {
+	db := memdb.New()
+	dl := directory.NewDirectoryLayer(subspace.Sub("nodes"), subspace.Sub("content"), false)
+
+	dir, e := dl.CreateOrOpen(db, []string{"dir"}, nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	partition, e := dl.CreateOrOpen(db, []string{"partition"}, []byte("partition"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	/* A directory is used as a subspace */
+	k, e := dir.PackE(tuple.Tuple{"a", 1})
+	if e != nil || !bytes.Equal(k, dir.Pack(tuple.Tuple{"a", 1})) {
+		t.Errorf("PackE of a directory returned (%x, %v), expected (%x, nil)", k, e, dir.Pack(tuple.Tuple{"a", 1}))
+	}
+	if _, e := dir.PackE(tuple.Tuple{"a", struct{}{}}); e == nil {
+		t.Error("PackE of a directory with an unencodable element succeeded")
+	}
+	sub, e := dir.SubE("a", 1)
+	if e != nil || !bytes.Equal(sub.Bytes(), dir.Sub("a", 1).Bytes()) {
+		t.Errorf("SubE of a directory returned (%v, %v)", sub, e)
+	}
+
+	/* The root of a partition refuses to be used as a subspace */
+	if k, e := partition.PackE(tuple.Tuple{"a", 1}); e == nil {
+		t.Errorf("PackE of a partition returned %x, expected an error", k)
+	}
+	if sub, e := partition.SubE("a", 1); e == nil {
+		t.Errorf("SubE of a partition returned %v, expected an error", sub)
+	}
+	if !panics(func() { partition.Pack(tuple.Tuple{"a", 1}) }) {
+		t.Error("Pack of a partition did not panic")
+	}
+	if !panics(func() { partition.Sub("a", 1) }) {
+		t.Error("Sub of a partition did not panic")
+	}
+
+	/* Directories within a partition are used as subspaces as usual */
+	inner, e := partition.CreateOrOpen(db, []string{"inner"}, nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e := inner.PackE(tuple.Tuple{"a", 1}); e != nil {
+		t.Errorf("PackE of a directory within a partition: %v", e)
+	}
+}
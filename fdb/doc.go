@@ -148,6 +148,19 @@ Note that (Transaction).Transact also recovers panics, but does not itself
 retry. If the recovered value is an FDB Error, it will be returned to the caller
 of (Transaction).Transact; all other values will be re-panicked.
 
+Typed Results
+
+The Transact and ReadTransact methods return the value returned by the
+transactional function as an interface{}, which must be type-asserted by the
+caller. The generic Transact and ReadTransact functions behave identically, but
+return a value of the type returned by the transactional function:
+
+    values, e := fdb.Transact(db, func (tr Transaction) ([]string, error) {
+        valueOne := tr.Get(fdb.Key("foo")).MustGet()
+        valueTwo := tr.Get(fdb.Key("bar")).MustGet()
+        return []string{string(valueOne), string(valueTwo)}, nil
+    })
+
 Transactions and Goroutines
 
 When using a Transactor in the fdb package, particular care must be taken if
@@ -187,6 +200,14 @@ reasonable default balance. Other streaming modes that prioritize throughput or
 latency are available -- see the documented StreamingMode values for specific
 options.
 
+By default, an iterator over a range requests each batch once the previous
+batch has been consumed, so a large range costs a round trip per batch. Setting
+the ReadAhead field of RangeOptions divides the range at its split points into
+chunks that are read concurrently, up to ReadAhead at a time, while the
+key-value pairs are still returned in order; ReadAheadBytes bounds the memory
+held by batches read ahead. Reads are only issued while the iterator is being
+advanced.
+
 Atomic Operations
 
 The FDB package provides a number of atomic operations on the Database and
@@ -204,5 +225,19 @@ package.
 
 The current atomic operations in this API are Add, BitAnd, BitOr and BitXor (all
 methods on Transaction).
+
+Alternative Backends
+
+By default, every Database, Transaction and Snapshot is backed by the
+FoundationDB C library. A Database may instead be constructed with NewDatabase
+from any implementation of the DatabaseBackend interface, such as the in-memory
+database provided by the memdb package for use in tests. Since such a Database
+satisfies the Transactor and ReadTransactor interfaces, transactional functions
+(and layers such as the subspace and directory packages) work unchanged with any
+backend.
+
+When built without cgo (CGO_ENABLED=0), the fdb package does not require the
+FoundationDB C library, and functions such as Open and APIVersion return an
+error; only a Database constructed with NewDatabase is usable.
 */
 package fdb
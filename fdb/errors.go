@@ -21,13 +21,8 @@
 
 package fdb
 
-/*
- #define FDB_API_VERSION 200
- #include <foundationdb/fdb_c.h>
-*/
-import "C"
-
 import (
+	"errors"
 	"fmt"
 )
 
@@ -35,24 +30,82 @@ import (
 // Error may be returned by any FoundationDB API function that returns error, or
 // as a panic from any FoundationDB API function whose name ends with OrPanic.
 //
-// You may compare the Code field of an Error against the list of FoundationDB
-// error codes at https://foundationdb.com/documentation/api-error-codes.html,
-// but generally an Error should be passed to (Transaction).OnError. When using
-// (Database).Transact, non-fatal errors will be retried automatically.
+// You may compare an Error against the ErrXxx variables of this package (each
+// corresponding to one of the FoundationDB error codes listed at
+// https://foundationdb.com/documentation/api-error-codes.html), preferably with
+// errors.Is so that wrapped errors are recognized. Generally an Error should be
+// passed to (Transaction).OnError. When using (Database).Transact, non-fatal
+// errors will be retried automatically.
 type Error struct {
 	Code int
 }
 
 func (e Error) Error() string {
-	return fmt.Sprintf("FoundationDB error code %d (%s)", e.Code, C.GoString(C.fdb_get_error(C.fdb_error_t(e.Code))))
+	return fmt.Sprintf("FoundationDB error code %d (%s)", e.Code, errorDescription(e.Code))
 }
 
-// SOMEDAY: these (along with others) should be coming from fdb.options?
+// Name returns the symbolic name of the error code (such as "not_committed"),
+// or the empty string if the code is not known to this package.
+func (e Error) Name() string {
+	return errorNames[e.Code]
+}
 
-var (
-	errNetworkNotSetup = Error{2008}
+// Is allows errors.Is to match an Error against either an Error or an *Error
+// with the same code.
+func (e Error) Is(target error) bool {
+	switch t := target.(type) {
+	case Error:
+		return e.Code == t.Code
+	case *Error:
+		return t != nil && e.Code == t.Code
+	}
+	return false
+}
 
-	errAPIVersionUnset = Error{2200}
-	errAPIVersionAlreadySet = Error{2201}
-	errAPIVersionNotSupported = Error{2203}
-)
+func asError(e error) (Error, bool) {
+	var fe Error
+	if errors.As(e, &fe) {
+		return fe, true
+	}
+	return Error{}, false
+}
+
+// IsRetryable returns true if e is (or wraps) an Error after which a
+// transaction may be retried. This is the case if either IsMaybeCommitted or
+// IsRetryableNotCommitted returns true.
+func IsRetryable(e error) bool {
+	return IsMaybeCommitted(e) || IsRetryableNotCommitted(e)
+}
+
+// IsMaybeCommitted returns true if e is (or wraps) an Error indicating that the
+// transaction may or may not have been committed, such as
+// ErrCommitUnknownResult. Retrying such a transaction is only safe if the
+// transaction is idempotent.
+func IsMaybeCommitted(e error) bool {
+	fe, ok := asError(e)
+	if !ok {
+		return false
+	}
+
+	switch fe {
+	case ErrCommitUnknownResult, ErrClusterVersionChanged:
+		return true
+	}
+	return false
+}
+
+// IsRetryableNotCommitted returns true if e is (or wraps) an Error indicating
+// that the transaction was definitely not committed and may be safely retried,
+// such as ErrNotCommitted.
+func IsRetryableNotCommitted(e error) bool {
+	fe, ok := asError(e)
+	if !ok {
+		return false
+	}
+
+	switch fe {
+	case ErrTransactionTooOld, ErrFutureVersion, ErrNotCommitted, ErrProcessBehind, ErrDatabaseLocked, ErrProxyMemoryLimitExceeded, ErrBatchTransactionThrottled, ErrTagThrottled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package fdb_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		e fdb.Error
+		name string
+		retryable, maybeCommitted, retryableNotCommitted bool
+	}{
+		{fdb.Error{Code: 1007}, "transaction_too_old", true, false, true},
+		{fdb.Error{Code: 1020}, "not_committed", true, false, true},
+		{fdb.Error{Code: 1021}, "commit_unknown_result", true, true, false},
+		{fdb.Error{Code: 1039}, "cluster_version_changed", true, true, false},
+		{fdb.Error{Code: 2000}, "client_invalid_operation", false, false, false},
+	}
+
+	for _, tt := range tests {
+		if name := tt.e.Name(); name != tt.name {
+			t.Errorf("error %d has name %q, expected %q", tt.e.Code, name, tt.name)
+		}
+
+		/* The predicates see through any wrapping of the error */
+		for _, e := range []error{tt.e, fmt.Errorf("wrapped: %w", tt.e), fmt.Errorf("twice: %w", fmt.Errorf("wrapped: %w", tt.e))} {
+			if r := fdb.IsRetryable(e); r != tt.retryable {
+				t.Errorf("IsRetryable(%v) = %v", e, r)
+			}
+			if r := fdb.IsMaybeCommitted(e); r != tt.maybeCommitted {
+				t.Errorf("IsMaybeCommitted(%v) = %v", e, r)
+			}
+			if r := fdb.IsRetryableNotCommitted(e); r != tt.retryableNotCommitted {
+				t.Errorf("IsRetryableNotCommitted(%v) = %v", e, r)
+			}
+			if r := fdb.ErrorPredicateRetryable.Test(e); r != tt.retryable {
+				t.Errorf("ErrorPredicateRetryable.Test(%v) = %v", e, r)
+			}
+			if r := fdb.ErrorPredicateMaybeCommitted.Test(e); r != tt.maybeCommitted {
+				t.Errorf("ErrorPredicateMaybeCommitted.Test(%v) = %v", e, r)
+			}
+			if r := fdb.ErrorPredicateRetryableNotCommitted.Test(e); r != tt.retryableNotCommitted {
+				t.Errorf("ErrorPredicateRetryableNotCommitted.Test(%v) = %v", e, r)
+			}
+		}
+	}
+
+	e := errors.New("not an fdb error")
+	if fdb.IsRetryable(e) || fdb.IsMaybeCommitted(e) || fdb.IsRetryableNotCommitted(e) {
+		t.Errorf("predicates hold for %v", e)
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	wrapped := fmt.Errorf("commit: %w", fmt.Errorf("attempt 3: %w", fdb.Error{Code: 1020}))
+
+	tests := []struct {
+		target error
+		is bool
+	}{
+		{fdb.ErrNotCommitted, true},
+		{fdb.Error{Code: 1020}, true},
+		{&fdb.Error{Code: 1020}, true},
+		{fdb.ErrCommitUnknownResult, false},
+		{&fdb.Error{Code: 1021}, false},
+		{(*fdb.Error)(nil), false},
+		{errors.New("not_committed"), false},
+	}
+
+	for _, tt := range tests {
+		if is := errors.Is(wrapped, tt.target); is != tt.is {
+			t.Errorf("errors.Is(%v, %#v) = %v", wrapped, tt.target, is)
+		}
+	}
+
+	var fe fdb.Error
+	if !errors.As(wrapped, &fe) || fe.Code != 1020 {
+		t.Errorf("errors.As(%v) found %v", wrapped, fe)
+	}
+}
@@ -0,0 +1,68 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"context"
+	"testing"
+)
+
+// selectAPIVersion makes version the selected API version for the duration of
+// a test, without selecting it in the C library.
+func selectAPIVersion(t *testing.T, version int) {
+	networkMutex.Lock()
+	old := apiVersion
+	apiVersion = version
+	networkMutex.Unlock()
+
+	t.Cleanup(func() {
+		networkMutex.Lock()
+		apiVersion = old
+		networkMutex.Unlock()
+	})
+}
+
+// SelectAPIVersion is selectAPIVersion, for use by the tests of package
+// fdb_test.
+var SelectAPIVersion = selectAPIVersion
+
+// ScanSplit reads kr as a split of a parallel scan of d, returning the number
+// of key-value pairs read and the error that stopped the scan of the split.
+func ScanSplit(d Database, kr KeyRange) (n int, e error) {
+	ch := make(chan scanBatch)
+	go func() {
+		scanSplit(context.Background(), d, kr, ch)
+		close(ch)
+	}()
+
+	for b := range ch {
+		n += len(b.kvs)
+		if b.err != nil {
+			e = b.err
+		}
+	}
+	return
+}
+
+// SetReadAheadChunkBytes is setReadAheadChunkBytes, for use by the tests of
+// package fdb_test.
+var SetReadAheadChunkBytes = setReadAheadChunkBytes
@@ -0,0 +1,145 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// FaultInjection configures the errors injected into the transactions of a
+// Database handle returned by (Database).WithFaultInjection.
+type FaultInjection struct {
+	// Seed seeds the pseudo-random source that decides which operations fail
+	// (and with which error). A program issuing the same operations in the same
+	// order observes the same failures on every run.
+	Seed int64
+
+	// Probability is the probability, between 0 and 1, that any single Get,
+	// GetRange or Commit fails with an injected error.
+	Probability float64
+}
+
+// WithFaultInjection returns a copy of the Database handle whose transactions
+// randomly fail with retryable errors, in the manner of the FoundationDB
+// simulator. Reads (including each batch of a range read) fail with
+// ErrTransactionTooOld or ErrFutureVersion, and commits fail with
+// ErrNotCommitted, ErrTransactionTooOld or ErrCommitUnknownResult. When
+// ErrCommitUnknownResult is injected, the transaction is committed in about half
+// of the cases, as may happen when a real commit result is lost.
+//
+// Running a transactional function through the Transact method of the returned
+// Database exercises the retry loop, and will reveal functions that are not
+// idempotent or that rely on side effects outside of the transaction. Faults
+// are injected regardless of the underlying DatabaseBackend, so a
+// fault-injecting in-memory database may be used in tests:
+//
+//     db := memdb.New().WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.1})
+//
+// The receiver (and any other handle to the same database) is unaffected.
+func (d Database) WithFaultInjection(fi FaultInjection) Database {
+	f := &faults{r: rand.New(rand.NewSource(fi.Seed)), p: fi.Probability}
+	d.database = &database{&faultDatabase{d.backend, f}}
+	return d
+}
+
+var (
+	readFaults = []Error{ErrTransactionTooOld, ErrFutureVersion}
+	commitFaults = []Error{ErrNotCommitted, ErrTransactionTooOld, ErrCommitUnknownResult}
+)
+
+type faults struct {
+	mu sync.Mutex
+	r *rand.Rand
+	p float64
+}
+
+// inject returns the error to inject into an operation, if any, chosen from
+// errs.
+func (f *faults) inject(errs []Error) (Error, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.r.Float64() >= f.p {
+		return Error{}, false
+	}
+	return errs[f.r.Intn(len(errs))], true
+}
+
+// coin returns the result of a fair coin toss.
+func (f *faults) coin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.r.Intn(2) == 0
+}
+
+type faultDatabase struct {
+	DatabaseBackend
+	f *faults
+}
+
+func (d *faultDatabase) CreateTransaction() (TransactionBackend, error) {
+	tb, e := d.DatabaseBackend.CreateTransaction()
+	if e != nil {
+		return nil, e
+	}
+	return &faultTransaction{tb, d.f}, nil
+}
+
+type faultTransaction struct {
+	TransactionBackend
+	f *faults
+}
+
+func (t *faultTransaction) Get(key Key, snapshot bool) FutureByteSlice {
+	if e, ok := t.f.inject(readFaults); ok {
+		return NewFutureByteSlice(nil, func() ([]byte, error) { return nil, e }, nil)
+	}
+	return t.TransactionBackend.Get(key, snapshot)
+}
+
+func (t *faultTransaction) GetRange(begin, end KeySelector, options RangeOptions, snapshot bool, iteration int) FutureKeyValueArray {
+	if e, ok := t.f.inject(readFaults); ok {
+		return NewFutureKeyValueArray(nil, func() ([]KeyValue, bool, error) { return nil, false, e }, nil)
+	}
+	return t.TransactionBackend.GetRange(begin, end, options, snapshot, iteration)
+}
+
+func (t *faultTransaction) Commit() FutureNil {
+	e, ok := t.f.inject(commitFaults)
+	if !ok {
+		return t.TransactionBackend.Commit()
+	}
+
+	if e == ErrCommitUnknownResult && t.f.coin() {
+		f := t.TransactionBackend.Commit()
+		return NewFutureNil(f.Ready(), func() error {
+			if e := f.Get(); e != nil {
+				return e
+			}
+			return ErrCommitUnknownResult
+		}, f.Cancel)
+	}
+
+	return NewFutureNil(nil, func() error { return e }, nil)
+}
@@ -0,0 +1,103 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package fdb_test
+
+import (
+	"testing"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+// faultSequence performs a fixed sequence of reads and commits against a new
+// in-memory database with the provided fault injection, returning the error
+// (or nil) of each operation.
+func faultSequence(t *testing.T, fi fdb.FaultInjection) []error {
+	db := memdb.New().WithFaultInjection(fi)
+
+	var errs []error
+	for i := 0; i < 50; i++ {
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		_, e = tr.Get(fdb.Key("key")).Get()
+		errs = append(errs, e)
+
+		_, e = tr.GetRange(fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("z")}, fdb.RangeOptions{}).GetSliceWithError()
+		errs = append(errs, e)
+
+		tr.Set(fdb.Key("key"), []byte{byte(i)})
+		errs = append(errs, tr.Commit().Get())
+	}
+
+	return errs
+}
+
+func TestFaultInjectionSeed(t *testing.T) {
+	a := faultSequence(t, fdb.FaultInjection{Seed: 42, Probability: 0.3})
+	b := faultSequence(t, fdb.FaultInjection{Seed: 42, Probability: 0.3})
+	c := faultSequence(t, fdb.FaultInjection{Seed: 43, Probability: 0.3})
+
+	var injected int
+	same, other := true, true
+	for i := range a {
+		if a[i] != nil {
+			injected++
+			if !fdb.IsRetryable(a[i]) {
+				t.Errorf("operation %d failed with %v, which is not retryable", i, a[i])
+			}
+		}
+		if a[i] != b[i] {
+			same = false
+		}
+		if a[i] != c[i] {
+			other = false
+		}
+	}
+
+	if injected == 0 {
+		t.Fatal("no faults injected")
+	}
+	if !same {
+		t.Errorf("the same seed injected different faults:\n%v\n%v", a, b)
+	}
+	if other {
+		t.Errorf("different seeds injected the same faults: %v", a)
+	}
+}
+
+func TestFaultInjectionProbability(t *testing.T) {
+	for i, e := range faultSequence(t, fdb.FaultInjection{Seed: 1, Probability: 0}) {
+		if e != nil {
+			t.Errorf("operation %d failed with %v at probability 0", i, e)
+		}
+	}
+
+	for i, e := range faultSequence(t, fdb.FaultInjection{Seed: 1, Probability: 1}) {
+		if e == nil {
+			t.Errorf("operation %d succeeded at probability 1", i)
+		}
+	}
+}
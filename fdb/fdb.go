@@ -21,28 +21,12 @@
 
 package fdb
 
-/*
- #define FDB_API_VERSION 200
- #include <foundationdb/fdb_c.h>
- #include <stdlib.h>
-*/
-import "C"
-
 import (
 	"fmt"
-	"runtime"
+	"reflect"
 	"sync"
-	"unsafe"
 )
 
-/* Would put this in futures.go but for the documented issue with
-/* exports and functions in preamble
-/* (https://code.google.com/p/go-wiki/wiki/cgo#Global_functions) */
-//export notifyChannel
-func notifyChannel(ch *chan struct{}) {
-	close(*ch)
-}
-
 // A Transactor can execute a function that requires a Transaction. Functions
 // written to accept a Transactor are called transactional functions, and may be
 // called with either a Database or a Transaction.
@@ -68,12 +52,58 @@ type ReadTransactor interface {
 	ReadTransact(func(ReadTransaction) (interface{}, error)) (interface{}, error)
 }
 
-func setOpt(setter func(*C.uint8_t, C.int) C.fdb_error_t, param []byte) error {
-	if err := setter(byteSliceToPtr(param), C.int(len(param))); err != 0 {
-		return Error{int(err)}
+// Transact executes the caller-provided function with the Transactor t,
+// returning its result as a value of type T. Since the result need not be
+// type-asserted, Transact is often more convenient than calling t.Transact
+// directly:
+//
+//     count, e := fdb.Transact(db, func(tr fdb.Transaction) (int, error) {
+//         kvs, e := tr.GetRange(r, fdb.RangeOptions{}).GetSliceWithError()
+//         return len(kvs), e
+//     })
+//
+// The function is retried and its panics are converted to errors exactly as by
+// t.Transact. If an error is returned, the returned value of type T is the
+// zero value. If t returns a value that is neither nil nor of type T, as only a
+// Transactor that does not return the result of the function may, an error is
+// returned.
+func Transact[T any](t Transactor, f func(Transaction) (T, error)) (T, error) {
+	ret, e := t.Transact(func(tr Transaction) (interface{}, error) {
+		return f(tr)
+	})
+	if e != nil {
+		var zero T
+		return zero, e
+	}
+	return result[T](ret)
+}
+
+// ReadTransact executes the caller-provided function with the ReadTransactor
+// rt, returning its result as a value of type T. See Transact for details.
+func ReadTransact[T any](rt ReadTransactor, f func(ReadTransaction) (T, error)) (T, error) {
+	ret, e := rt.ReadTransact(func(rtr ReadTransaction) (interface{}, error) {
+		return f(rtr)
+	})
+	if e != nil {
+		var zero T
+		return zero, e
+	}
+	return result[T](ret)
+}
+
+// result returns the value returned by a transactional function of Transact or
+// ReadTransact as a value of type T. A nil value, as returned by a function
+// whose result is a nil interface, is the zero value.
+func result[T any](ret interface{}) (T, error) {
+	var zero T
+	if ret == nil {
+		return zero, nil
+	}
+	v, ok := ret.(T)
+	if !ok {
+		return zero, fmt.Errorf("transactional function returned %T, expected %v", ret, reflect.TypeOf(&zero).Elem())
 	}
-
-	return nil
+	return v, nil
 }
 
 // NetworkOptions is a handle with which to set options that affect the entire
@@ -88,50 +118,11 @@ func Options() NetworkOptions {
 	return NetworkOptions{}
 }
 
-func (opt NetworkOptions) setOpt(code int, param []byte) error {
-	networkMutex.Lock()
-	defer networkMutex.Unlock()
-
-	if apiVersion == 0 {
-		return errAPIVersionUnset
-	}
-
-	return setOpt(func(p *C.uint8_t, pl C.int) C.fdb_error_t {
-		return C.fdb_network_set_option(C.FDBNetworkOption(code), p, pl)
-	}, param)
-}
-
-// APIVersion determines the runtime behavior the fdb package. If the requested
-// version is not supported by both the fdb package and the FoundationDB C
-// library, an error will be returned. APIVersion must be called prior to any
-// other functions in the fdb package.
-//
-// Currently, only API version 200 is supported.
-func APIVersion(version int) error {
-	networkMutex.Lock()
-	defer networkMutex.Unlock()
-
-	if apiVersion != 0 {
-		if apiVersion == version {
-			return nil
-		}
-		return errAPIVersionAlreadySet
-	}
+// networkOptionSetter sets a network option; it is replaced in tests.
+var networkOptionSetter = setNetworkOption
 
-	if version < 200 || version > 200 {
-		return errAPIVersionNotSupported
-	}
-
-	if e := C.fdb_select_api_version_impl(C.int(version), 200); e != 0 {
-		if e == 2203 {
-			return fmt.Errorf("API version %d not supported by the installed FoundationDB C library", version)
-		}
-		return Error{int(e)}
-	}
-
-	apiVersion = version
-
-	return nil
+func (opt NetworkOptions) setOpt(code int, param []byte) error {
+	return networkOptionSetter(code, param)
 }
 
 // MustAPIVersion is like APIVersion but panics if the API version is not
@@ -143,45 +134,36 @@ func MustAPIVersion(version int) {
 	}
 }
 
-var apiVersion int
-var networkStarted bool
-var networkMutex sync.Mutex
-
-var openClusters map[string]Cluster
-var openDatabases map[string]Database
-
-func init() {
-	openClusters = make(map[string]Cluster)
-	openDatabases = make(map[string]Database)
-}
+// GetAPIVersion returns the API version selected by APIVersion, or
+// ErrAPIVersionUnset if no API version has been selected. Without cgo, no API
+// version can be selected.
+func GetAPIVersion() (int, error) {
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
 
-func startNetwork() error {
-	if e := C.fdb_setup_network(); e != 0 {
-		return Error{int(e)}
+	if apiVersion == 0 {
+		return 0, ErrAPIVersionUnset
 	}
-
-	go C.fdb_run_network()
-
-	networkStarted = true
-
-	return nil
+	return apiVersion, nil
 }
 
-// StartNetwork initializes the FoundationDB client networking engine. It is not
-// necessary to call StartNetwork when using the fdb.Open or fdb.OpenDefault
-// functions to obtain a database handle. StartNetwork must not be called more
-// than once.
-func StartNetwork() error {
+// requireAPIVersion returns an error if the selected API version predates
+// version, the API version in which a function or option was introduced. If
+// no API version has been selected (as when using only a Database constructed
+// with NewDatabase), there is nothing to check.
+func requireAPIVersion(version int) error {
 	networkMutex.Lock()
 	defer networkMutex.Unlock()
 
-	if apiVersion == 0 {
-		return errAPIVersionUnset
+	if apiVersion != 0 && apiVersion < version {
+		return fmt.Errorf("%w: API version %d required (API version %d selected)", ErrAPIVersionNotSupported, version, apiVersion)
 	}
-
-	return startNetwork()
+	return nil
 }
 
+var apiVersion int
+var networkMutex sync.Mutex
+
 // DefaultClusterFile should be passed to fdb.Open or fdb.CreateCluster to allow
 // the FoundationDB C library to select the platform-appropriate default cluster
 // file on the current machine.
@@ -205,50 +187,6 @@ func MustOpenDefault() Database {
 	return db
 }
 
-// Open returns a database handle to the named database from the FoundationDB
-// cluster identified by the provided cluster file and database name. The
-// FoundationDB client networking engine will be initialized first, if
-// necessary.
-//
-// In the current release, the database name must be []byte("DB").
-func Open(clusterFile string, dbName []byte) (Database, error) {
-	networkMutex.Lock()
-	defer networkMutex.Unlock()
-
-	if apiVersion == 0 {
-		return Database{}, errAPIVersionUnset
-	}
-
-	var e error
-
-	if !networkStarted {
-		e = startNetwork()
-		if e != nil {
-			return Database{}, e
-		}
-	}
-
-	cluster, ok := openClusters[clusterFile]
-	if !ok {
-		cluster, e = createCluster(clusterFile)
-		if e != nil {
-			return Database{}, e
-		}
-		openClusters[clusterFile] = cluster
-	}
-
-	db, ok := openDatabases[string(dbName)]
-	if !ok {
-		db, e = cluster.OpenDatabase(dbName)
-		if e != nil {
-			return Database{}, e
-		}
-		openDatabases[string(dbName)] = db
-	}
-
-	return db, nil
-}
-
 // MustOpen is like Open but panics if the database cannot be opened.
 func MustOpen(clusterFile string, dbName []byte) Database {
 	db, err := Open(clusterFile, dbName)
@@ -258,56 +196,6 @@ func MustOpen(clusterFile string, dbName []byte) Database {
 	return db
 }
 
-func createCluster(clusterFile string) (Cluster, error) {
-	var cf *C.char
-
-	if len(clusterFile) != 0 {
-		cf = C.CString(clusterFile)
-		defer C.free(unsafe.Pointer(cf))
-	}
-
-	f := C.fdb_create_cluster(cf)
-	fdb_future_block_until_ready(f)
-
-	var outc *C.FDBCluster
-
-	if err := C.fdb_future_get_cluster(f, &outc); err != 0 {
-		return Cluster{}, Error{int(err)}
-	}
-
-	C.fdb_future_destroy(f)
-
-	c := &cluster{outc}
-	runtime.SetFinalizer(c, (*cluster).destroy)
-
-	return Cluster{c}, nil
-}
-
-// CreateCluster returns a cluster handle to the FoundationDB cluster identified
-// by the provided cluster file.
-func CreateCluster(clusterFile string) (Cluster, error) {
-	networkMutex.Lock()
-	defer networkMutex.Unlock()
-
-	if apiVersion == 0 {
-		return Cluster{}, errAPIVersionUnset
-	}
-
-	if !networkStarted {
-		return Cluster{}, errNetworkNotSetup
-	}
-
-	return createCluster(clusterFile)
-}
-
-func byteSliceToPtr(b []byte) *C.uint8_t {
-	if len(b) > 0 {
-		return (*C.uint8_t)(unsafe.Pointer(&b[0]))
-	} else {
-		return nil
-	}
-}
-
 // A KeyConvertible can be converted to a FoundationDB Key. All functions in the
 // FoundationDB API that address a specific key accept a KeyConvertible.
 type KeyConvertible interface {
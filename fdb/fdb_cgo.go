@@ -0,0 +1,185 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+/*
+ #include <foundationdb/fdb_c.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+)
+
+/* Would put this in futures_cgo.go but for the documented issue with
+/* exports and functions in preamble
+/* (https://code.google.com/p/go-wiki/wiki/cgo#Global_functions) */
+//export notifyChannel
+func notifyChannel(h C.uintptr_t) {
+	ch := cgo.Handle(h)
+	close(ch.Value().(chan struct{}))
+	ch.Delete()
+}
+
+func setOpt(setter func(*C.uint8_t, C.int) C.fdb_error_t, param []byte) error {
+	if err := setter(byteSliceToPtr(param), C.int(len(param))); err != 0 {
+		return Error{int(err)}
+	}
+
+	return nil
+}
+
+func setNetworkOption(code int, param []byte) error {
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
+
+	if apiVersion == 0 {
+		return ErrAPIVersionUnset
+	}
+
+	return setOpt(func(p *C.uint8_t, pl C.int) C.fdb_error_t {
+		return C.fdb_network_set_option(C.FDBNetworkOption(code), p, pl)
+	}, param)
+}
+
+// APIVersion determines the runtime behavior the fdb package. If the requested
+// version is not supported by both the fdb package and the FoundationDB C
+// library, an error will be returned. APIVersion must be called prior to any
+// other functions in the fdb package.
+//
+// The fdb package supports API versions from 200 up to the version of the
+// FoundationDB C API header it was built against. By default this is API
+// version 200; a newer header version may be selected at build time with one
+// of the build tags fdb_api_610, fdb_api_620, fdb_api_630, fdb_api_700 or
+// fdb_api_710 (for example, go build -tags fdb_api_710). Functions that were
+// introduced after API version 200 return an error wrapping
+// ErrAPIVersionNotSupported if an older API version has been selected.
+func APIVersion(version int) error {
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
+
+	if apiVersion != 0 {
+		if apiVersion == version {
+			return nil
+		}
+		return ErrAPIVersionAlreadySet
+	}
+
+	if version < 200 || version > headerAPIVersion {
+		return ErrAPIVersionNotSupported
+	}
+
+	if e := C.fdb_select_api_version_impl(C.int(version), C.int(headerAPIVersion)); e != 0 {
+		if e == 2203 {
+			return fmt.Errorf("API version %d not supported by the installed FoundationDB C library (maximum supported version %d)", version, int(C.fdb_get_max_api_version()))
+		}
+		return Error{int(e)}
+	}
+
+	apiVersion = version
+
+	return nil
+}
+
+var networkStarted bool
+
+var openDatabases map[string]Database
+
+func init() {
+	openDatabases = make(map[string]Database)
+}
+
+func startNetwork() error {
+	if e := C.fdb_setup_network(); e != 0 {
+		return Error{int(e)}
+	}
+
+	go C.fdb_run_network()
+
+	networkStarted = true
+
+	return nil
+}
+
+// StartNetwork initializes the FoundationDB client networking engine. It is not
+// necessary to call StartNetwork when using the fdb.Open or fdb.OpenDefault
+// functions to obtain a database handle. StartNetwork must not be called more
+// than once.
+func StartNetwork() error {
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
+
+	if apiVersion == 0 {
+		return ErrAPIVersionUnset
+	}
+
+	return startNetwork()
+}
+
+// Open returns a database handle to the named database from the FoundationDB
+// cluster identified by the provided cluster file and database name. The
+// FoundationDB client networking engine will be initialized first, if
+// necessary.
+//
+// In the current release, the database name must be []byte("DB").
+func Open(clusterFile string, dbName []byte) (Database, error) {
+	networkMutex.Lock()
+	defer networkMutex.Unlock()
+
+	if apiVersion == 0 {
+		return Database{}, ErrAPIVersionUnset
+	}
+
+	var e error
+
+	if !networkStarted {
+		e = startNetwork()
+		if e != nil {
+			return Database{}, e
+		}
+	}
+
+	db, ok := openDatabases[string(dbName)]
+	if !ok {
+		db, e = openDatabase(clusterFile, dbName)
+		if e != nil {
+			return Database{}, e
+		}
+		openDatabases[string(dbName)] = db
+	}
+
+	return db, nil
+}
+
+func byteSliceToPtr(b []byte) *C.uint8_t {
+	if len(b) > 0 {
+		return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+	} else {
+		return nil
+	}
+}
+
+func errorDescription(code int) string {
+	return C.GoString(C.fdb_get_error(C.fdb_error_t(code)))
+}
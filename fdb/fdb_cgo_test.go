@@ -0,0 +1,141 @@
+//go:build cgo
+
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRequireAPIVersion(t *testing.T) {
+	tests := []struct {
+		selected, required int
+		ok bool
+	}{
+		{0, 710, true},
+		{200, 200, true},
+		{200, 410, false},
+		{410, 410, true},
+		{630, 620, true},
+		{630, 700, false},
+	}
+
+	for _, tt := range tests {
+		selectAPIVersion(t, tt.selected)
+		e := requireAPIVersion(tt.required)
+		if tt.ok && e != nil {
+			t.Errorf("requireAPIVersion(%d) at API version %d: %v", tt.required, tt.selected, e)
+		}
+		if !tt.ok && !errors.Is(e, ErrAPIVersionNotSupported) {
+			t.Errorf("requireAPIVersion(%d) at API version %d returned %v, expected ErrAPIVersionNotSupported", tt.required, tt.selected, e)
+		}
+	}
+}
+
+func TestAPIVersionGating(t *testing.T) {
+	selectAPIVersion(t, 200)
+
+	// The C transaction is never reached, as each function fails at API
+	// version 200
+	tr := &cTransaction{}
+
+	tests := []struct {
+		name string
+		get func() error
+	}{
+		{"GetVersionstamp", func() error { _, e := tr.GetVersionstamp().Get(); return e }},
+		{"GetApproximateSize", func() error { _, e := tr.GetApproximateSize().Get(); return e }},
+		{"GetEstimatedRangeSizeBytes", func() error { _, e := tr.GetEstimatedRangeSizeBytes(Key("a"), Key("b")).Get(); return e }},
+		{"GetRangeSplitPoints", func() error { _, e := tr.GetRangeSplitPoints(Key("a"), Key("b"), 1000).Get(); return e }},
+	}
+
+	for _, tt := range tests {
+		if e := tt.get(); !errors.Is(e, ErrAPIVersionNotSupported) {
+			t.Errorf("%s at API version 200 returned %v, expected ErrAPIVersionNotSupported", tt.name, e)
+		}
+	}
+}
+
+func TestBlockUntilReadyContext(t *testing.T) {
+	if e := APIVersion(200); e != nil && e != ErrAPIVersionAlreadySet {
+		t.Skip(e)
+	}
+	db, e := OpenDefault()
+	if e != nil {
+		t.Skipf("no database available: %v", e)
+	}
+
+	tests := []struct {
+		name string
+		ctx func() (context.Context, context.CancelFunc)
+		err error
+	}{
+		{"cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}, context.Canceled},
+		{"deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 5*time.Millisecond)
+		}, context.DeadlineExceeded},
+	}
+
+	before := runtime.NumGoroutine()
+
+	for _, tt := range tests {
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		/* The watch of an uncommitted transaction never becomes ready */
+		w := tr.Watch(Key("watched"))
+
+		ctx, cancel := tt.ctx()
+		e = w.GetContext(ctx)
+		cancel()
+
+		if !errors.Is(e, tt.err) || !errors.Is(e, ErrOperationCancelled) {
+			t.Errorf("%s: GetContext returned %v, expected %v and ErrOperationCancelled", tt.name, e, tt.err)
+		}
+
+		/* Cancelling the future runs its callback, releasing the handle
+		/* to its ready channel */
+		select {
+		case <-w.Ready():
+		case <-time.After(time.Second):
+			t.Errorf("%s: future not ready after GetContext cancelled it", tt.name)
+		}
+		if e := w.Get(); !errors.Is(e, ErrOperationCancelled) {
+			t.Errorf("%s: cancelled future returned %v, expected ErrOperationCancelled", tt.name, e)
+		}
+	}
+
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left running by GetContext", n-before)
+	}
+}
@@ -0,0 +1,79 @@
+//go:build !cgo
+
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package fdb
+
+import (
+	"errors"
+)
+
+// Without cgo, the FoundationDB C library is unavailable and the fdb package
+// may only be used with a Database constructed by NewDatabase from an
+// alternative DatabaseBackend. The functions below, which require the C
+// library, return errNoCgo.
+
+var errNoCgo = errors.New("the FoundationDB C library is not available (the fdb package was built without cgo)")
+
+func setNetworkOption(code int, param []byte) error {
+	return errNoCgo
+}
+
+// APIVersion determines the runtime behavior the fdb package. Without cgo,
+// APIVersion always returns an error.
+func APIVersion(version int) error {
+	return errNoCgo
+}
+
+// StartNetwork initializes the FoundationDB client networking engine. Without
+// cgo, StartNetwork always returns an error.
+func StartNetwork() error {
+	return errNoCgo
+}
+
+// Open returns a database handle to the named database from the FoundationDB
+// cluster identified by the provided cluster file and database name. Without
+// cgo, Open always returns an error.
+func Open(clusterFile string, dbName []byte) (Database, error) {
+	return Database{}, errNoCgo
+}
+
+// Cluster is a handle to a FoundationDB cluster. Without cgo, a Cluster cannot
+// be created.
+type Cluster struct {
+}
+
+// OpenDatabase returns a database handle from the FoundationDB
+// cluster. Without cgo, OpenDatabase always returns an error.
+func (c Cluster) OpenDatabase(dbName []byte) (Database, error) {
+	return Database{}, errNoCgo
+}
+
+// CreateCluster returns a cluster handle to the FoundationDB cluster identified
+// by the provided cluster file. Without cgo, CreateCluster always returns an
+// error.
+func CreateCluster(clusterFile string) (Cluster, error) {
+	return Cluster{}, errNoCgo
+}
+
+func errorDescription(code int) string {
+	return errorNames[code]
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 
 	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
 )
 
 func ExampleOpenDefault() {
@@ -225,3 +226,117 @@ func ExampleRangeIterator() {
 	// banana is bar
 	// cherry is baz
 }
+
+func ExampleRangeResult_All() {
+	fdb.MustAPIVersion(200)
+	db := fdb.MustOpenDefault()
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		fmt.Printf("Unable to create transaction: %v\n", e)
+		return
+	}
+
+	// Clear and initialize data in this transaction. In examples we do not
+	// commit transactions to avoid mutating a real database.
+	tr.ClearRange(fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}})
+	tr.Set(fdb.Key("apple"), []byte("foo"))
+	tr.Set(fdb.Key("cherry"), []byte("baz"))
+	tr.Set(fdb.Key("banana"), []byte("bar"))
+
+	// Batches are read as the loop needs them, and the loop may exit early
+	for kv, e := range tr.GetRange(fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}}, fdb.RangeOptions{}).All() {
+		if e != nil {
+			fmt.Printf("Unable to read next value: %v\n", e)
+			return
+		}
+		if string(kv.Key) == "cherry" {
+			break
+		}
+		fmt.Printf("%s is %s\n", kv.Key, kv.Value)
+	}
+
+	// Output:
+	// apple is foo
+	// banana is bar
+}
+
+func ExampleParallelScan() {
+	// An in-memory database stands in for a real one in this example.
+	db := memdb.New()
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		for i := 0; i < 100; i++ {
+			tr.Set(fdb.Key(fmt.Sprintf("key%03d", i)), []byte("value"))
+		}
+		return nil, nil
+	})
+	if e != nil {
+		fmt.Printf("Unable to perform transaction: %v\n", e)
+		return
+	}
+
+	// Count the key-value pairs in the database, and their total size, reading
+	// up to 8 shards at once. The function is never called concurrently.
+	var count, size int
+	e = fdb.ParallelScan(db, fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}}, 8, func(kv fdb.KeyValue) error {
+		count += 1
+		size += len(kv.Key) + len(kv.Value)
+		return nil
+	})
+	if e != nil {
+		fmt.Printf("Unable to scan database: %v\n", e)
+		return
+	}
+
+	fmt.Printf("%d key-value pairs (%d bytes)\n", count, size)
+
+	// Output:
+	// 100 key-value pairs (1100 bytes)
+}
+
+func ExampleScanAll() {
+	// An in-memory database stands in for a real one in this example.
+	db := memdb.New()
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Set(fdb.Key("apple"), []byte("foo"))
+		tr.Set(fdb.Key("cherry"), []byte("baz"))
+		tr.Set(fdb.Key("banana"), []byte("bar"))
+		return nil, nil
+	})
+	if e != nil {
+		fmt.Printf("Unable to perform transaction: %v\n", e)
+		return
+	}
+
+	// A saved continuation token (nil to begin a new scan), such as one
+	// persisted by an earlier run of this program.
+	var token []byte
+
+	s := fdb.ScanAll(db, fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}}, fdb.ScanOptions{Continuation: token, TransactionLimit: 2})
+	if s.Next() {
+		kv := s.KeyValue()
+		fmt.Printf("%s is %s\n", kv.Key, kv.Value)
+	}
+
+	// Stop after the first key, saving the position of the scan
+	token = s.Continuation()
+
+	// Resume the scan after the last key printed
+	s = fdb.ScanAll(db, fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}}, fdb.ScanOptions{Continuation: token, TransactionLimit: 2})
+	for s.Next() {
+		kv := s.KeyValue()
+		fmt.Printf("%s is %s\n", kv.Key, kv.Value)
+	}
+	if e := s.Err(); e != nil {
+		// Persist the token to resume the scan after the last key printed
+		token = s.Continuation()
+		fmt.Printf("Scan stopped (resume from %x): %v\n", token, e)
+	}
+
+	// Output:
+	// apple is foo
+	// banana is bar
+	// cherry is baz
+}
@@ -21,28 +21,10 @@
 
 package fdb
 
-/*
- #cgo LDFLAGS: -lfdb_c -lm
- #define FDB_API_VERSION 200
- #include <foundationdb/fdb_c.h>
- #include <string.h>
-
- extern void notifyChannel(void*);
-
- void go_callback(FDBFuture* f, void* ch) {
-     notifyChannel(ch);
- }
-
- void go_set_callback(void* f, void* ch) {
-     fdb_future_set_callback(f, (FDBCallback)&go_callback, ch);
- }
-*/
-import "C"
-
 import (
-	"runtime"
+	"context"
+	"reflect"
 	"sync"
-	"unsafe"
 )
 
 // A Future represents a value (or error) to be available at some later
@@ -61,6 +43,11 @@ type Future interface {
 	// enclosed type (if any) or has been set to an error state.
 	IsReady() bool
 
+	// Ready returns a channel that is closed when the future becomes ready,
+	// allowing futures to be used in select statements. Every call to Ready
+	// on a future returns the same channel.
+	Ready() <-chan struct{}
+
 	// Cancel cancels a future and its associated asynchronous operation. If
 	// called before the future becomes ready, attempts to access the future
 	// will return an error. Cancel has no effect if the future is already
@@ -71,88 +58,106 @@ type Future interface {
 	Cancel()
 }
 
-type future struct {
-	ptr *C.FDBFuture
-}
-
-func newFuture(ptr *C.FDBFuture) *future {
-	f := &future{ptr}
-	runtime.SetFinalizer(f, func(f *future) { C.fdb_future_destroy(f.ptr) })
-	return f
+// WaitAll blocks the calling goroutine until all of the provided futures are
+// ready.
+func WaitAll(futures ...Future) {
+	for _, f := range futures {
+		f.BlockUntilReady()
+	}
 }
 
-func fdb_future_block_until_ready(f *C.FDBFuture) *chan struct{} {
-	if C.fdb_future_is_ready(f) != 0 {
-		return nil
+// WaitAny blocks the calling goroutine until at least one of the provided
+// futures is ready, and returns the index of a ready future. WaitAny returns -1
+// if no futures are provided.
+func WaitAny(futures ...Future) int {
+	if len(futures) == 0 {
+		return -1
 	}
 
-	ch := make(chan struct{}, 1)
-	C.go_set_callback(unsafe.Pointer(f), unsafe.Pointer(&ch))
-	<-ch
-	return &ch
-}
+	for i, f := range futures {
+		if f.IsReady() {
+			return i
+		}
+	}
 
-func (f future) BlockUntilReady() {
-	fdb_future_block_until_ready(f.ptr)
-}
+	cases := make([]reflect.SelectCase, len(futures))
+	for i, f := range futures {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.Ready())}
+	}
 
-func (f future) IsReady() bool {
-	return C.fdb_future_is_ready(f.ptr) != 0
+	i, _, _ := reflect.Select(cases)
+	return i
 }
 
-func (f future) Cancel() {
-	C.fdb_future_cancel(f.ptr)
-}
+// FutureValue represents the asynchronous result of type T of a function or a
+// composition of futures. FutureByteSlice, FutureKey, FutureInt64,
+// FutureStringSlice and FutureKeyArray all satisfy FutureValue for their
+// respective value types, and futures of any of these types may be composed
+// with Then.
+type FutureValue[T any] interface {
+	// Get returns the value of the future, or an error if the asynchronous
+	// operation associated with this future did not successfully
+	// complete. The current goroutine will be blocked until the future is
+	// ready.
+	Get() (T, error)
 
-// FutureByteSlice represents the asynchronous result of a function that returns
-// a value from a database. FutureByteSlice is a lightweight object that may be
-// efficiently copied, and is safe for concurrent use by multiple goroutines.
-type FutureByteSlice interface {
-	// Get returns a database value (or nil if there is no value), or an error
-	// if the asynchronous operation associated with this future did not
-	// successfully complete. The current goroutine will be blocked until the
-	// future is ready.
-	Get() ([]byte, error)
+	// MustGet returns the value of the future, or panics if the asynchronous
+	// operation associated with this future did not successfully
+	// complete. The current goroutine will be blocked until the future is
+	// ready.
+	MustGet() T
 
-	// MustGet returns a database value (or nil if there is no value), or panics
-	// if the asynchronous operation associated with this future did not
-	// successfully complete. The current goroutine will be blocked until the
-	// future is ready.
-	MustGet() []byte
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) (T, error)
 
 	Future
 }
 
-type futureByteSlice struct {
-	*future
-	v []byte
+type futureThen[T, U any] struct {
+	FutureValue[T]
+	fn func(T) (U, error)
+	v U
 	e error
 	o sync.Once
 }
 
-func (f *futureByteSlice) Get() ([]byte, error) {
-	f.o.Do(func() {
-		var present C.fdb_bool_t
-		var value *C.uint8_t
-		var length C.int
+// Then returns a FutureValue that becomes ready when f becomes ready, and whose
+// value is the result of applying fn to the value of f. If f completes with an
+// error, fn is not called and the returned future reports the same error. fn
+// is called at most once, by the first call to Get, MustGet or GetContext on
+// the returned future. If the context passed to GetContext is done before f is
+// ready, f is cancelled, fn is not called, and the returned future reports the
+// error returned by GetContext.
+//
+// Cancelling the returned future cancels f.
+func Then[T, U any](f FutureValue[T], fn func(T) (U, error)) FutureValue[U] {
+	return &futureThen[T, U]{FutureValue: f, fn: fn}
+}
 
-		f.BlockUntilReady()
+func (f *futureThen[T, U]) Get() (U, error) {
+	return f.apply(f.FutureValue.Get())
+}
 
-		if err := C.fdb_future_get_value(f.ptr, &present, &value, &length); err != 0 {
-			f.e = Error{int(err)}
-		} else {
-			if present != 0 {
-				f.v = C.GoBytes(unsafe.Pointer(value), length)
-			}
-		}
+func (f *futureThen[T, U]) GetContext(ctx context.Context) (U, error) {
+	return f.apply(f.FutureValue.GetContext(ctx))
+}
 
-		C.fdb_future_release_memory(f.ptr)
+// apply records the result of applying fn to the value of f, or the error of
+// f, unless a result has already been recorded, and returns the result.
+func (f *futureThen[T, U]) apply(v T, e error) (U, error) {
+	f.o.Do(func() {
+		f.e = e
+		if f.e == nil {
+			f.v, f.e = f.fn(v)
+		}
 	})
 
 	return f.v, f.e
 }
 
-func (f *futureByteSlice) MustGet() []byte {
+func (f *futureThen[T, U]) MustGet() U {
 	val, err := f.Get()
 	if err != nil {
 		panic(err)
@@ -160,6 +165,30 @@ func (f *futureByteSlice) MustGet() []byte {
 	return val
 }
 
+// FutureByteSlice represents the asynchronous result of a function that returns
+// a value from a database. FutureByteSlice is a lightweight object that may be
+// efficiently copied, and is safe for concurrent use by multiple goroutines.
+type FutureByteSlice interface {
+	// Get returns a database value (or nil if there is no value), or an error
+	// if the asynchronous operation associated with this future did not
+	// successfully complete. The current goroutine will be blocked until the
+	// future is ready.
+	Get() ([]byte, error)
+
+	// MustGet returns a database value (or nil if there is no value), or panics
+	// if the asynchronous operation associated with this future did not
+	// successfully complete. The current goroutine will be blocked until the
+	// future is ready.
+	MustGet() []byte
+
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) ([]byte, error)
+
+	Future
+}
+
 // FutureKey represents the asynchronous result of a function that returns a key
 // from a database. FutureKey is a lightweight object that may be efficiently
 // copied, and is safe for concurrent use by multiple goroutines.
@@ -174,41 +203,12 @@ type FutureKey interface {
 	// goroutine will be blocked until the future is ready.
 	MustGet() Key
 
-	Future
-}
-
-type futureKey struct {
-	*future
-	k Key
-	e error
-	o sync.Once
-}
-
-func (f *futureKey) Get() (Key, error) {
-	f.o.Do(func() {
-		var value *C.uint8_t
-		var length C.int
-
-		f.BlockUntilReady()
-
-		if err := C.fdb_future_get_key(f.ptr, &value, &length); err != 0 {
-			f.e = Error{int(err)}
-		} else {
-			f.k = C.GoBytes(unsafe.Pointer(value), length)
-		}
-
-		C.fdb_future_release_memory(f.ptr)
-	})
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) (Key, error)
 
-	return f.k, f.e
-}
-
-func (f *futureKey) MustGet() Key {
-	val, err := f.Get()
-	if err != nil {
-		panic(err)
-	}
-	return val
+	Future
 }
 
 // FutureNil represents the asynchronous result of a function that has no return
@@ -225,66 +225,27 @@ type FutureNil interface {
 	// until the future is ready.
 	MustGet()
 
-	Future
-}
-
-type futureNil struct {
-	*future
-}
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) error
 
-func (f futureNil) Get() error {
-	f.BlockUntilReady()
-	if err := C.fdb_future_get_error(f.ptr); err != 0 {
-		return Error{int(err)}
-	}
-
-	return nil
-}
-
-func (f futureNil) MustGet() {
-	if err := f.Get(); err != nil {
-		panic(err)
-	}
-}
-
-type futureKeyValueArray struct {
-	*future
-}
-
-func stringRefToSlice(ptr unsafe.Pointer) []byte {
-	size := *((*C.int)(unsafe.Pointer(uintptr(ptr) + 8)))
-
-	if size == 0 {
-		return []byte{}
-	}
-
-	src := unsafe.Pointer(*(**C.uint8_t)(unsafe.Pointer(ptr)))
-
-	return C.GoBytes(src, size)
+	Future
 }
 
-func (f futureKeyValueArray) Get() ([]KeyValue, bool, error) {
-	f.BlockUntilReady()
-
-	var kvs *C.void
-	var count C.int
-	var more C.fdb_bool_t
-
-	if err := C.fdb_future_get_keyvalue_array(f.ptr, (**C.FDBKeyValue)(unsafe.Pointer(&kvs)), &count, &more); err != 0 {
-		return nil, false, Error{int(err)}
-	}
-
-	ret := make([]KeyValue, int(count))
-
-	for i := 0; i < int(count); i++ {
-		kvptr := unsafe.Pointer(uintptr(unsafe.Pointer(kvs)) + uintptr(i*24))
-		vptr := unsafe.Pointer(uintptr(unsafe.Pointer(kvs)) + uintptr(i*24+12))
-
-		ret[i].Key = stringRefToSlice(kvptr)
-		ret[i].Value = stringRefToSlice(vptr)
-	}
+// FutureKeyValueArray represents the asynchronous result of reading a single
+// batch of key-value pairs from a range. Most code should use the RangeResult
+// returned by a GetRange method rather than FutureKeyValueArray, which is
+// primarily of interest to implementations of TransactionBackend.
+type FutureKeyValueArray interface {
+	// Get returns the key-value pairs of the batch and whether more key-value
+	// pairs may remain in the range beyond those returned, or an error if the
+	// asynchronous operation associated with this future did not successfully
+	// complete. The current goroutine will be blocked until the future is
+	// ready.
+	Get() ([]KeyValue, bool, error)
 
-	return ret, (more != 0), nil
+	Future
 }
 
 // FutureInt64 represents the asynchronous result of a function that returns a
@@ -301,29 +262,12 @@ type FutureInt64 interface {
 	// current goroutine will be blocked until the future is ready.
 	MustGet() int64
 
-	Future
-}
-
-type futureInt64 struct {
-	*future
-}
-
-func (f futureInt64) Get() (int64, error) {
-	f.BlockUntilReady()
-
-	var ver C.int64_t
-	if err := C.fdb_future_get_version(f.ptr, &ver); err != 0 {
-		return 0, Error{int(err)}
-	}
-	return int64(ver), nil
-}
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) (int64, error)
 
-func (f futureInt64) MustGet() int64 {
-	val, err := f.Get()
-	if err != nil {
-		panic(err)
-	}
-	return val
+	Future
 }
 
 // FutureStringSlice represents the asynchronous result of a function that
@@ -341,36 +285,197 @@ type FutureStringSlice interface {
 	// current goroutine will be blocked until the future is ready.
 	MustGet() []string
 
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) ([]string, error)
+
 	Future
 }
 
-type futureStringSlice struct {
-	*future
+// FutureKeyArray represents the asynchronous result of a function that returns
+// a slice of keys. FutureKeyArray is a lightweight object that may be
+// efficiently copied, and is safe for concurrent use by multiple goroutines.
+type FutureKeyArray interface {
+	// Get returns a slice of keys or an error if the asynchronous operation
+	// associated with this future did not successfully complete. The current
+	// goroutine will be blocked until the future is ready.
+	Get() ([]Key, error)
+
+	// MustGet returns a slice of keys or panics if the asynchronous operation
+	// associated with this future did not successfully complete. The current
+	// goroutine will be blocked until the future is ready.
+	MustGet() []Key
+
+	// GetContext is like Get, but cancels the future and returns an error
+	// wrapping both ctx.Err() and ErrOperationCancelled if ctx is done before
+	// the future is ready.
+	GetContext(ctx context.Context) ([]Key, error)
+
+	Future
+}
+
+type goFuture[T any] struct {
+	ready <-chan struct{}
+	get func() (T, error)
+	cancel func()
+	v T
+	e error
+	o sync.Once
+}
+
+func newGoFuture[T any](ready <-chan struct{}, get func() (T, error), cancel func()) *goFuture[T] {
+	if ready == nil {
+		ch := make(chan struct{})
+		close(ch)
+		ready = ch
+	}
+	return &goFuture[T]{ready: ready, get: get, cancel: cancel}
+}
+
+func (f *goFuture[T]) BlockUntilReady() {
+	<-f.ready
 }
 
-func (f futureStringSlice) Get() ([]string, error) {
-	f.BlockUntilReady()
+func (f *goFuture[T]) IsReady() bool {
+	select {
+	case <-f.ready:
+		return true
+	default:
+		return false
+	}
+}
 
-	var strings **C.char
-	var count C.int
+func (f *goFuture[T]) Ready() <-chan struct{} {
+	return f.ready
+}
 
-	if err := C.fdb_future_get_string_array(f.ptr, (***C.char)(unsafe.Pointer(&strings)), &count); err != 0 {
-		return nil, Error{int(err)}
+func (f *goFuture[T]) Cancel() {
+	if f.cancel != nil && !f.IsReady() {
+		f.cancel()
 	}
+}
 
-	ret := make([]string, int(count))
+func (f *goFuture[T]) Get() (T, error) {
+	<-f.ready
+	f.o.Do(func() {
+		f.v, f.e = f.get()
+	})
+	return f.v, f.e
+}
 
-	for i := 0; i < int(count); i++ {
-		ret[i] = C.GoString((*C.char)(*(**C.char)(unsafe.Pointer(uintptr(unsafe.Pointer(strings)) + uintptr(i*8)))))
+func (f *goFuture[T]) GetContext(ctx context.Context) (T, error) {
+	/* A ready future is never cancelled, even if ctx is also done */
+	if f.IsReady() {
+		return f.Get()
 	}
 
-	return ret, nil
+	select {
+	case <-f.ready:
+	case <-ctx.Done():
+		f.Cancel()
+		var zero T
+		return zero, cancelledError(ctx)
+	}
+	return f.Get()
+}
+
+// cancelledError returns the error of a future cancelled because ctx is done,
+// which wraps both ctx.Err() and ErrOperationCancelled.
+func cancelledError(ctx context.Context) error {
+	return contextError(ctx, ErrOperationCancelled)
 }
 
-func (f futureStringSlice) MustGet() []string {
+func (f *goFuture[T]) MustGet() T {
 	val, err := f.Get()
 	if err != nil {
 		panic(err)
 	}
 	return val
 }
+
+type goFutureNil struct {
+	*goFuture[struct{}]
+}
+
+func (f goFutureNil) Get() error {
+	_, e := f.goFuture.Get()
+	return e
+}
+
+func (f goFutureNil) GetContext(ctx context.Context) error {
+	_, e := f.goFuture.GetContext(ctx)
+	return e
+}
+
+func (f goFutureNil) MustGet() {
+	if err := f.Get(); err != nil {
+		panic(err)
+	}
+}
+
+type keyValueBatch struct {
+	kvs []KeyValue
+	more bool
+}
+
+type goFutureKeyValueArray struct {
+	*goFuture[keyValueBatch]
+}
+
+func (f goFutureKeyValueArray) Get() ([]KeyValue, bool, error) {
+	b, e := f.goFuture.Get()
+	return b.kvs, b.more, e
+}
+
+// NewFutureByteSlice returns a FutureByteSlice implemented in Go, for use by
+// implementations of TransactionBackend. The future becomes ready when the
+// ready channel is closed (or immediately, if ready is nil), after which its
+// value is obtained from a single call to get. If cancel is non-nil, it is
+// called when the future is cancelled before becoming ready, and should cause
+// the ready channel to be closed (typically with get then returning
+// ErrOperationCancelled).
+func NewFutureByteSlice(ready <-chan struct{}, get func() ([]byte, error), cancel func()) FutureByteSlice {
+	return newGoFuture(ready, get, cancel)
+}
+
+// NewFutureKey returns a FutureKey implemented in Go. See NewFutureByteSlice
+// for the meaning of the arguments.
+func NewFutureKey(ready <-chan struct{}, get func() (Key, error), cancel func()) FutureKey {
+	return newGoFuture(ready, get, cancel)
+}
+
+// NewFutureNil returns a FutureNil implemented in Go. See NewFutureByteSlice
+// for the meaning of the arguments.
+func NewFutureNil(ready <-chan struct{}, get func() error, cancel func()) FutureNil {
+	return goFutureNil{newGoFuture(ready, func() (struct{}, error) {
+		return struct{}{}, get()
+	}, cancel)}
+}
+
+// NewFutureInt64 returns a FutureInt64 implemented in Go. See
+// NewFutureByteSlice for the meaning of the arguments.
+func NewFutureInt64(ready <-chan struct{}, get func() (int64, error), cancel func()) FutureInt64 {
+	return newGoFuture(ready, get, cancel)
+}
+
+// NewFutureStringSlice returns a FutureStringSlice implemented in Go. See
+// NewFutureByteSlice for the meaning of the arguments.
+func NewFutureStringSlice(ready <-chan struct{}, get func() ([]string, error), cancel func()) FutureStringSlice {
+	return newGoFuture(ready, get, cancel)
+}
+
+// NewFutureKeyArray returns a FutureKeyArray implemented in Go. See
+// NewFutureByteSlice for the meaning of the arguments.
+func NewFutureKeyArray(ready <-chan struct{}, get func() ([]Key, error), cancel func()) FutureKeyArray {
+	return newGoFuture(ready, get, cancel)
+}
+
+// NewFutureKeyValueArray returns a FutureKeyValueArray implemented in Go. See
+// NewFutureByteSlice for the meaning of the arguments.
+func NewFutureKeyValueArray(ready <-chan struct{}, get func() ([]KeyValue, bool, error), cancel func()) FutureKeyValueArray {
+	return goFutureKeyValueArray{newGoFuture(ready, func() (keyValueBatch, error) {
+		kvs, more, e := get()
+		return keyValueBatch{kvs, more}, e
+	}, cancel)}
+}
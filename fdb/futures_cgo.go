@@ -0,0 +1,399 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+/*
+ #cgo LDFLAGS: -lfdb_c -lm
+ #include <foundationdb/fdb_c.h>
+ #include <stdint.h>
+ #include <string.h>
+
+ extern void notifyChannel(uintptr_t);
+
+ void go_callback(FDBFuture* f, void* h) {
+     notifyChannel((uintptr_t)h);
+ }
+
+ void go_set_callback(void* f, uintptr_t h) {
+     fdb_future_set_callback(f, (FDBCallback)&go_callback, (void*)h);
+ }
+
+ fdb_error_t go_future_get_int64(FDBFuture* f, int64_t* out) {
+ #if FDB_API_VERSION >= 620
+     return fdb_future_get_int64(f, out);
+ #else
+     return fdb_future_get_version(f, out);
+ #endif
+ }
+
+ fdb_error_t go_future_get_key_array(FDBFuture* f, FDBKey const** out_key_array, int* out_count) {
+ #if FDB_API_VERSION >= 700
+     return fdb_future_get_key_array(f, out_key_array, out_count);
+ #else
+     return 2000;
+ #endif
+ }
+*/
+import "C"
+
+import (
+	"context"
+	"runtime"
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+type future struct {
+	ptr *C.FDBFuture
+
+	// ready is closed by the callback of the C future once it is ready. The
+	// callback is set by the first call to Ready, and every wait on the future
+	// shares it.
+	ready chan struct{}
+	o sync.Once
+}
+
+func newFuture(ptr *C.FDBFuture) *future {
+	f := &future{ptr: ptr}
+	runtime.SetFinalizer(f, func(f *future) { C.fdb_future_destroy(f.ptr) })
+	return f
+}
+
+// fdb_future_set_channel arranges for ch to be closed once f is ready. The C
+// library holds a handle to ch, rather than a Go pointer, until the callback is
+// called.
+func fdb_future_set_channel(f *C.FDBFuture, ch chan struct{}) {
+	C.go_set_callback(unsafe.Pointer(f), C.uintptr_t(cgo.NewHandle(ch)))
+}
+
+func fdb_future_block_until_ready(f *C.FDBFuture) {
+	if C.fdb_future_is_ready(f) != 0 {
+		return
+	}
+
+	ch := make(chan struct{})
+	fdb_future_set_channel(f, ch)
+	<-ch
+}
+
+// blockUntilReadyContext is like BlockUntilReady, but cancels the future and
+// returns an error wrapping both ctx.Err() and ErrOperationCancelled if ctx is
+// done before the future becomes ready.
+func (f *future) blockUntilReadyContext(ctx context.Context) error {
+	if f.IsReady() {
+		return nil
+	}
+
+	if ctx.Err() != nil {
+		C.fdb_future_cancel(f.ptr)
+		return cancelledError(ctx)
+	}
+
+	select {
+	case <-f.Ready():
+		return nil
+	case <-ctx.Done():
+		C.fdb_future_cancel(f.ptr)
+		return cancelledError(ctx)
+	}
+}
+
+func (f *future) BlockUntilReady() {
+	<-f.Ready()
+}
+
+func (f *future) IsReady() bool {
+	return C.fdb_future_is_ready(f.ptr) != 0
+}
+
+func (f *future) Ready() <-chan struct{} {
+	f.o.Do(func() {
+		f.ready = make(chan struct{})
+		if f.IsReady() {
+			close(f.ready)
+			return
+		}
+		fdb_future_set_channel(f.ptr, f.ready)
+	})
+	return f.ready
+}
+
+func (f *future) Cancel() {
+	C.fdb_future_cancel(f.ptr)
+}
+
+type futureByteSlice struct {
+	*future
+	v []byte
+	e error
+	o sync.Once
+}
+
+func (f *futureByteSlice) Get() ([]byte, error) {
+	f.o.Do(func() {
+		var present C.fdb_bool_t
+		var value *C.uint8_t
+		var length C.int
+
+		f.BlockUntilReady()
+
+		if err := C.fdb_future_get_value(f.ptr, &present, &value, &length); err != 0 {
+			f.e = Error{int(err)}
+		} else {
+			if present != 0 {
+				f.v = C.GoBytes(unsafe.Pointer(value), length)
+			}
+		}
+
+		C.fdb_future_release_memory(f.ptr)
+	})
+
+	return f.v, f.e
+}
+
+func (f *futureByteSlice) GetContext(ctx context.Context) ([]byte, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.Get()
+}
+
+func (f *futureByteSlice) MustGet() []byte {
+	val, err := f.Get()
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+type futureKey struct {
+	*future
+	k Key
+	e error
+	o sync.Once
+}
+
+func (f *futureKey) Get() (Key, error) {
+	f.o.Do(func() {
+		var value *C.uint8_t
+		var length C.int
+
+		f.BlockUntilReady()
+
+		if err := C.fdb_future_get_key(f.ptr, &value, &length); err != 0 {
+			f.e = Error{int(err)}
+		} else {
+			f.k = C.GoBytes(unsafe.Pointer(value), length)
+		}
+
+		C.fdb_future_release_memory(f.ptr)
+	})
+
+	return f.k, f.e
+}
+
+func (f *futureKey) GetContext(ctx context.Context) (Key, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.Get()
+}
+
+func (f *futureKey) MustGet() Key {
+	val, err := f.Get()
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+type futureNil struct {
+	*future
+}
+
+func (f futureNil) Get() error {
+	f.BlockUntilReady()
+	if err := C.fdb_future_get_error(f.ptr); err != 0 {
+		return Error{int(err)}
+	}
+
+	return nil
+}
+
+func (f futureNil) GetContext(ctx context.Context) error {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return e
+	}
+	return f.Get()
+}
+
+func (f futureNil) MustGet() {
+	if err := f.Get(); err != nil {
+		panic(err)
+	}
+}
+
+type futureKeyValueArray struct {
+	*future
+}
+
+func stringRefToSlice(ptr unsafe.Pointer) []byte {
+	size := *((*C.int)(unsafe.Pointer(uintptr(ptr) + 8)))
+
+	if size == 0 {
+		return []byte{}
+	}
+
+	src := unsafe.Pointer(*(**C.uint8_t)(unsafe.Pointer(ptr)))
+
+	return C.GoBytes(src, size)
+}
+
+func (f futureKeyValueArray) Get() ([]KeyValue, bool, error) {
+	f.BlockUntilReady()
+
+	var kvs *C.void
+	var count C.int
+	var more C.fdb_bool_t
+
+	if err := C.fdb_future_get_keyvalue_array(f.ptr, (**C.FDBKeyValue)(unsafe.Pointer(&kvs)), &count, &more); err != 0 {
+		return nil, false, Error{int(err)}
+	}
+
+	ret := make([]KeyValue, int(count))
+
+	for i := 0; i < int(count); i++ {
+		kvptr := unsafe.Pointer(uintptr(unsafe.Pointer(kvs)) + uintptr(i*24))
+		vptr := unsafe.Pointer(uintptr(unsafe.Pointer(kvs)) + uintptr(i*24+12))
+
+		ret[i].Key = stringRefToSlice(kvptr)
+		ret[i].Value = stringRefToSlice(vptr)
+	}
+
+	return ret, (more != 0), nil
+}
+
+type futureInt64 struct {
+	*future
+}
+
+func (f futureInt64) Get() (int64, error) {
+	f.BlockUntilReady()
+
+	var ver C.int64_t
+	if err := C.go_future_get_int64(f.ptr, &ver); err != 0 {
+		return 0, Error{int(err)}
+	}
+	return int64(ver), nil
+}
+
+func (f futureInt64) GetContext(ctx context.Context) (int64, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return 0, e
+	}
+	return f.Get()
+}
+
+func (f futureInt64) MustGet() int64 {
+	val, err := f.Get()
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+type futureStringSlice struct {
+	*future
+}
+
+func (f futureStringSlice) Get() ([]string, error) {
+	f.BlockUntilReady()
+
+	var strings **C.char
+	var count C.int
+
+	if err := C.fdb_future_get_string_array(f.ptr, (***C.char)(unsafe.Pointer(&strings)), &count); err != 0 {
+		return nil, Error{int(err)}
+	}
+
+	ret := make([]string, int(count))
+
+	for i := 0; i < int(count); i++ {
+		ret[i] = C.GoString((*C.char)(*(**C.char)(unsafe.Pointer(uintptr(unsafe.Pointer(strings)) + uintptr(i*8)))))
+	}
+
+	return ret, nil
+}
+
+func (f futureStringSlice) GetContext(ctx context.Context) ([]string, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.Get()
+}
+
+func (f futureStringSlice) MustGet() []string {
+	val, err := f.Get()
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+type futureKeyArray struct {
+	*future
+}
+
+func (f futureKeyArray) Get() ([]Key, error) {
+	f.BlockUntilReady()
+
+	var keys *C.FDBKey
+	var count C.int
+
+	if err := C.go_future_get_key_array(f.ptr, &keys, &count); err != 0 {
+		return nil, Error{int(err)}
+	}
+
+	ret := make([]Key, int(count))
+
+	for i, k := range unsafe.Slice(keys, int(count)) {
+		ret[i] = C.GoBytes(unsafe.Pointer(k.key), k.key_length)
+	}
+
+	return ret, nil
+}
+
+func (f futureKeyArray) GetContext(ctx context.Context) ([]Key, error) {
+	if e := f.blockUntilReadyContext(ctx); e != nil {
+		return nil, e
+	}
+	return f.Get()
+}
+
+func (f futureKeyArray) MustGet() []Key {
+	val, err := f.Get()
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
@@ -0,0 +1,361 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package fdb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+func TestWaitAny(t *testing.T) {
+	db := memdb.New()
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	/* A watch that never fires */
+	w := tr.Watch(fdb.Key("watched"))
+	if e := tr.Commit().Get(); e != nil {
+		t.Fatal(e)
+	}
+	defer w.Cancel()
+
+	ready := make(chan struct{})
+	f := fdb.NewFutureNil(ready, func() error { return nil }, nil)
+
+	done := make(chan int)
+	go func() {
+		done <- fdb.WaitAny(w, f)
+	}()
+
+	select {
+	case i := <-done:
+		t.Fatalf("WaitAny returned %d before any future was ready", i)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(ready)
+	if i := <-done; i != 1 {
+		t.Errorf("WaitAny returned %d, expected 1", i)
+	}
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		if i := fdb.WaitAny(w, f); i != 1 {
+			t.Fatalf("WaitAny returned %d, expected 1", i)
+		}
+	}
+
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left running by WaitAny", n-before)
+	}
+
+	if i := fdb.WaitAny(); i != -1 {
+		t.Errorf("WaitAny of no futures returned %d, expected -1", i)
+	}
+}
+
+func TestReady(t *testing.T) {
+	db := memdb.New()
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	w := tr.Watch(fdb.Key("watched"))
+	if e := tr.Commit().Get(); e != nil {
+		t.Fatal(e)
+	}
+
+	if w.Ready() != w.Ready() {
+		t.Error("Ready returned different channels for the same future")
+	}
+
+	select {
+	case <-w.Ready():
+		t.Fatal("watch ready before its key changed")
+	default:
+	}
+
+	_, e = db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Set(fdb.Key("watched"), []byte("changed"))
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	select {
+	case <-w.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("watch not ready after its key changed")
+	}
+	if e := w.Get(); e != nil {
+		t.Error(e)
+	}
+}
+
+func TestWaitAll(t *testing.T) {
+	chs := make([]chan struct{}, 3)
+	fs := make([]fdb.Future, 3)
+	for i := range chs {
+		chs[i] = make(chan struct{})
+		fs[i] = fdb.NewFutureNil(chs[i], func() error { return nil }, nil)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		fdb.WaitAll(fs...)
+		close(done)
+	}()
+
+	for _, ch := range chs {
+		select {
+		case <-done:
+			t.Fatal("WaitAll returned before every future was ready")
+		case <-time.After(time.Millisecond):
+		}
+		close(ch)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("WaitAll did not return once every future was ready")
+	}
+}
+
+func TestThen(t *testing.T) {
+	ready := func(v int64, e error) fdb.FutureInt64 {
+		return fdb.NewFutureInt64(nil, func() (int64, error) { return v, e }, nil)
+	}
+
+	var calls int
+	format := func(v int64) (string, error) {
+		calls++
+		return fmt.Sprint(v), nil
+	}
+
+	tests := []struct {
+		name string
+		f fdb.FutureValue[string]
+		v string
+		err error
+		calls int
+	}{
+		{"value", fdb.Then(ready(42, nil), format), "42", nil, 1},
+		{"source error", fdb.Then(ready(42, fdb.ErrTransactionTooOld), format), "", fdb.ErrTransactionTooOld, 0},
+		{"fn error", fdb.Then(ready(42, nil), func(int64) (string, error) {
+			calls++
+			return "", errTemporary
+		}), "", errTemporary, 1},
+		{"composed", fdb.Then(fdb.Then(ready(42, nil), func(v int64) (int64, error) {
+			return v + 1, nil
+		}), format), "43", nil, 1},
+	}
+
+	for _, tt := range tests {
+		calls = 0
+		for i := 0; i < 2; i++ {
+			if v, e := tt.f.Get(); v != tt.v || e != tt.err {
+				t.Errorf("%s: Get returned (%q, %v), expected (%q, %v)", tt.name, v, e, tt.v, tt.err)
+			}
+			if v, e := tt.f.GetContext(context.Background()); v != tt.v || e != tt.err {
+				t.Errorf("%s: GetContext returned (%q, %v), expected (%q, %v)", tt.name, v, e, tt.v, tt.err)
+			}
+		}
+		if calls != tt.calls {
+			t.Errorf("%s: fn called %d times, expected %d", tt.name, calls, tt.calls)
+		}
+	}
+}
+
+func TestThenCancel(t *testing.T) {
+	var calls int
+	format := func(k fdb.Key) (string, error) {
+		calls++
+		return string(k), nil
+	}
+	source := func(p *pending) fdb.FutureKey {
+		return fdb.NewFutureKey(p.ready, func() (fdb.Key, error) { return nil, fdb.ErrOperationCancelled }, p.cancel)
+	}
+
+	p := newPending()
+	f := fdb.Then(source(p), format)
+	f.Cancel()
+	if p.cancels != 1 {
+		t.Errorf("Cancel cancelled the source future %d times, expected once", p.cancels)
+	}
+	if _, e := f.Get(); e != fdb.ErrOperationCancelled {
+		t.Errorf("cancelled future returned %v, expected ErrOperationCancelled", e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	p = newPending()
+	f = fdb.Then(source(p), format)
+	if _, e := f.GetContext(ctx); !errors.Is(e, context.DeadlineExceeded) || !errors.Is(e, fdb.ErrOperationCancelled) {
+		t.Errorf("GetContext returned %v, expected DeadlineExceeded and ErrOperationCancelled", e)
+	}
+	if p.cancels != 1 {
+		t.Errorf("GetContext cancelled the source future %d times, expected once", p.cancels)
+	}
+	if _, e := f.Get(); !errors.Is(e, context.DeadlineExceeded) {
+		t.Errorf("Get after GetContext returned %v, expected the error of GetContext", e)
+	}
+	if calls != 0 {
+		t.Errorf("fn called %d times on cancelled futures", calls)
+	}
+}
+
+// pending is the state of futures that never become ready unless cancelled.
+type pending struct {
+	ready chan struct{}
+	cancels int
+}
+
+func newPending() *pending {
+	return &pending{ready: make(chan struct{})}
+}
+
+func (p *pending) cancel() {
+	p.cancels++
+	close(p.ready)
+}
+
+func TestGetContext(t *testing.T) {
+	getters := []struct {
+		name string
+		get func(p *pending, ctx context.Context) error
+	}{
+		{"FutureByteSlice", func(p *pending, ctx context.Context) error {
+			_, e := fdb.NewFutureByteSlice(p.ready, func() ([]byte, error) { return nil, fdb.ErrOperationCancelled }, p.cancel).GetContext(ctx)
+			return e
+		}},
+		{"FutureKey", func(p *pending, ctx context.Context) error {
+			_, e := fdb.NewFutureKey(p.ready, func() (fdb.Key, error) { return nil, fdb.ErrOperationCancelled }, p.cancel).GetContext(ctx)
+			return e
+		}},
+		{"FutureNil", func(p *pending, ctx context.Context) error {
+			return fdb.NewFutureNil(p.ready, func() error { return fdb.ErrOperationCancelled }, p.cancel).GetContext(ctx)
+		}},
+		{"FutureInt64", func(p *pending, ctx context.Context) error {
+			_, e := fdb.NewFutureInt64(p.ready, func() (int64, error) { return 0, fdb.ErrOperationCancelled }, p.cancel).GetContext(ctx)
+			return e
+		}},
+		{"FutureStringSlice", func(p *pending, ctx context.Context) error {
+			_, e := fdb.NewFutureStringSlice(p.ready, func() ([]string, error) { return nil, fdb.ErrOperationCancelled }, p.cancel).GetContext(ctx)
+			return e
+		}},
+		{"FutureKeyArray", func(p *pending, ctx context.Context) error {
+			_, e := fdb.NewFutureKeyArray(p.ready, func() ([]fdb.Key, error) { return nil, fdb.ErrOperationCancelled }, p.cancel).GetContext(ctx)
+			return e
+		}},
+	}
+
+	contexts := []struct {
+		name string
+		ctx func() (context.Context, context.CancelFunc)
+		err error
+	}{
+		{"cancelled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}, context.Canceled},
+		{"cancelled while waiting", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(5*time.Millisecond, cancel)
+			return ctx, cancel
+		}, context.Canceled},
+		{"deadline", func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 5*time.Millisecond)
+		}, context.DeadlineExceeded},
+	}
+
+	before := runtime.NumGoroutine()
+
+	for _, g := range getters {
+		for _, c := range contexts {
+			p := newPending()
+			ctx, cancel := c.ctx()
+			e := g.get(p, ctx)
+			cancel()
+
+			if !errors.Is(e, c.err) || !errors.Is(e, fdb.ErrOperationCancelled) {
+				t.Errorf("%s with %s context returned %v, expected %v and ErrOperationCancelled", g.name, c.name, e, c.err)
+			}
+			if p.cancels != 1 {
+				t.Errorf("%s with %s context cancelled the future %d times, expected once", g.name, c.name, p.cancels)
+			}
+		}
+	}
+
+	/* A ready future is not cancelled, even if ctx is done */
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := newPending()
+	close(p.ready)
+	if v, e := fdb.NewFutureInt64(p.ready, func() (int64, error) { return 42, nil }, p.cancel).GetContext(ctx); v != 42 || e != nil {
+		t.Errorf("GetContext of a ready future returned (%d, %v), expected (42, nil)", v, e)
+	}
+
+	waitGoroutines(t, before)
+}
+
+func TestGetContextWatch(t *testing.T) {
+	db := memdb.New()
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	/* A watch that never fires */
+	w := tr.Watch(fdb.Key("watched"))
+	if e := tr.Commit().Get(); e != nil {
+		t.Fatal(e)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	if e := w.GetContext(ctx); !errors.Is(e, context.DeadlineExceeded) || !errors.Is(e, fdb.ErrOperationCancelled) {
+		t.Errorf("GetContext of a watch returned %v, expected DeadlineExceeded and ErrOperationCancelled", e)
+	}
+	if e := w.Get(); !errors.Is(e, fdb.ErrOperationCancelled) {
+		t.Errorf("watch cancelled by GetContext returned %v, expected ErrOperationCancelled", e)
+	}
+}
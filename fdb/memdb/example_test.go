@@ -0,0 +1,59 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package memdb_test
+
+import (
+	"fmt"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+func ExampleNew() {
+	db := memdb.New()
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Set(fdb.Key("hello"), []byte("world"))
+		tr.Add(fdb.Key("counter"), []byte{1, 0, 0, 0})
+		return nil, nil
+	})
+	if e != nil {
+		fmt.Printf("Unable to perform transaction: %v\n", e)
+		return
+	}
+
+	ret, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+		return rtr.GetRange(fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}}, fdb.RangeOptions{}).GetSliceWithError()
+	})
+	if e != nil {
+		fmt.Printf("Unable to read range: %v\n", e)
+		return
+	}
+
+	for _, kv := range ret.([]fdb.KeyValue) {
+		fmt.Printf("%s: %q\n", kv.Key, kv.Value)
+	}
+
+	// Output:
+	// counter: "\x01\x00\x00\x00"
+	// hello: "world"
+}
@@ -0,0 +1,306 @@
+// FoundationDB Go In-Memory Database
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package memdb provides an in-memory implementation of a FoundationDB
+// database, suitable for testing transactional functions (and layers built upon
+// them, such as the subspace and directory packages) without a FoundationDB
+// cluster. The fdb.Database returned by New may be used anywhere an
+// fdb.Transactor or fdb.ReadTransactor is accepted.
+//
+// The in-memory database keeps every committed version of every key, and
+// transactions read from a consistent snapshot at their read version. Reads
+// observe prior writes in the same transaction (other than versionstamped
+// mutations, whose effect is unknown until commit), key selectors are resolved
+// as by FoundationDB, and the Add, BitAnd, BitOr, BitXor, SetVersionstampedKey
+// and SetVersionstampedValue atomic operations are supported. Commits are
+// checked for conflicts against the read conflict ranges of the transaction,
+// and a transaction that conflicts with a transaction committed after its read
+// version fails with fdb.ErrNotCommitted (allowing (fdb.Database).Transact to
+// retry it). Watches become ready when a later commit changes the value of the
+// watched key.
+//
+// Old versions are discarded once no transaction may read them: a transaction
+// may read the versions from its read version on until it is committed, reset
+// or cancelled (or is no longer referenced). Only a transaction given an older
+// read version with SetReadVersion fails with fdb.ErrTransactionTooOld. Range
+// reads are not split by shard and (fdb.Database).LocalityGetBoundaryKeys
+// returns no boundaries. Options other than those controlling timeouts and
+// retry limits are ignored.
+package memdb
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+// New returns a handle to a new, empty in-memory database. The database is
+// discarded when it is no longer referenced.
+func New() fdb.Database {
+	return fdb.NewDatabase(&store{history: make(map[string][]entry)})
+}
+
+type entry struct {
+	version int64
+	value []byte
+	present bool
+}
+
+type keyRange struct {
+	begin, end []byte
+}
+
+func (r keyRange) intersects(o keyRange) bool {
+	return bytes.Compare(r.begin, o.end) < 0 && bytes.Compare(o.begin, r.end) < 0
+}
+
+type commitRecord struct {
+	version int64
+	writes []keyRange
+}
+
+type store struct {
+	mu sync.Mutex
+	version int64
+
+	// keys holds every key ever written, in order; history holds the
+	// committed entries of each key, in increasing version order.
+	keys []string
+	history map[string][]entry
+
+	commits []commitRecord
+	watches map[string][]*watch
+
+	// readers counts the transactions reading at each read version; versions
+	// before pruned have been discarded. written counts the keys written (and
+	// commits made) since the store was last pruned.
+	readers map[int64]int
+	pruned int64
+	written int
+}
+
+func (s *store) CreateTransaction() (fdb.TransactionBackend, error) {
+	return newTransaction(s), nil
+}
+
+func (s *store) SetOption(code int, param []byte) error {
+	return nil
+}
+
+// get returns the value of key as of version. The store must be locked.
+func (s *store) get(key string, version int64) ([]byte, bool) {
+	h := s.history[key]
+	i := sort.Search(len(h), func(i int) bool { return h[i].version > version })
+	if i == 0 {
+		return nil, false
+	}
+	return h[i-1].value, h[i-1].present
+}
+
+// keysBetween returns the keys ever written in [begin, end). A nil end is
+// unbounded. The store must be locked.
+func (s *store) keysBetween(begin, end []byte) []string {
+	i := sort.SearchStrings(s.keys, string(begin))
+	j := len(s.keys)
+	if end != nil {
+		j = sort.SearchStrings(s.keys, string(end))
+	}
+	if j < i {
+		return nil
+	}
+	return s.keys[i:j]
+}
+
+// conflicts returns true if any transaction committed after version wrote to
+// any of the provided ranges. The store must be locked.
+func (s *store) conflicts(version int64, reads []keyRange) bool {
+	i := sort.Search(len(s.commits), func(i int) bool { return s.commits[i].version > version })
+	for _, c := range s.commits[i:] {
+		for _, w := range c.writes {
+			for _, r := range reads {
+				if w.intersects(r) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// acquire registers a transaction reading at version, whose entries are
+// retained until it is released. The store must be locked.
+func (s *store) acquire(version int64) {
+	if s.readers == nil {
+		s.readers = make(map[int64]int)
+	}
+	s.readers[version]++
+}
+
+// release unregisters a transaction reading at version. The store must be
+// locked.
+func (s *store) release(version int64) {
+	if s.readers[version]--; s.readers[version] <= 0 {
+		delete(s.readers, version)
+	}
+}
+
+// prune discards the entries that no transaction may read: of the entries of
+// each key at or before the oldest version being read, only the latest is
+// kept, and keys whose latest such entry is a clear are forgotten, as are the
+// commits that no transaction may conflict with. So that pruning costs no more
+// than the writes, the store is pruned only once as many keys have been
+// written as it holds. The store must be locked.
+func (s *store) prune() {
+	if s.written < len(s.keys) {
+		return
+	}
+	s.written = 0
+
+	oldest := s.version
+	for v := range s.readers {
+		if v < oldest {
+			oldest = v
+		}
+	}
+	s.pruned = oldest
+
+	keys := s.keys[:0]
+	for _, k := range s.keys {
+		h := s.history[k]
+		i := sort.Search(len(h), func(i int) bool { return h[i].version > oldest })
+		if i == 1 && !h[0].present && len(h) == 1 {
+			delete(s.history, k)
+			continue
+		}
+		if i > 1 {
+			s.history[k] = append([]entry{}, h[i-1:]...)
+		}
+		keys = append(keys, k)
+	}
+	for i := len(keys); i < len(s.keys); i++ {
+		s.keys[i] = ""
+	}
+	s.keys = keys
+
+	i := sort.Search(len(s.commits), func(i int) bool { return s.commits[i].version > oldest })
+	s.commits = append([]commitRecord{}, s.commits[i:]...)
+}
+
+// write records the value of key at the current version. The store must be
+// locked.
+func (s *store) write(key string, value []byte, present bool) {
+	h, ok := s.history[key]
+	if !ok {
+		i := sort.SearchStrings(s.keys, key)
+		s.keys = append(s.keys, "")
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = key
+	}
+	s.history[key] = append(h, entry{s.version, value, present})
+
+	/* Fired watches are done with, and are no longer checked */
+	var pending []*watch
+	for _, w := range s.watches[key] {
+		if !w.check(value, present) {
+			pending = append(pending, w)
+		}
+	}
+	if len(pending) == 0 {
+		delete(s.watches, key)
+	} else {
+		s.watches[key] = pending
+	}
+}
+
+// addWatch registers a watch that becomes ready when the value of its key
+// differs from the value the watch was created with. The store must be locked.
+func (s *store) addWatch(w *watch) {
+	select {
+	case <-w.ready:
+		return
+	default:
+	}
+
+	v, ok := s.get(w.key, s.version)
+	if w.check(v, ok) {
+		return
+	}
+	if s.watches == nil {
+		s.watches = make(map[string][]*watch)
+	}
+	s.watches[w.key] = append(s.watches[w.key], w)
+}
+
+func (s *store) removeWatch(w *watch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ws := s.watches[w.key]
+	for i, o := range ws {
+		if o == w {
+			ws = append(ws[:i:i], ws[i+1:]...)
+			break
+		}
+	}
+	if len(ws) == 0 {
+		delete(s.watches, w.key)
+	} else {
+		s.watches[w.key] = ws
+	}
+}
+
+type watch struct {
+	s *store
+	key string
+	value []byte
+	present bool
+	ready chan struct{}
+	once sync.Once
+	e error
+}
+
+func (w *watch) fire(e error) {
+	w.once.Do(func() {
+		w.e = e
+		close(w.ready)
+	})
+}
+
+// check fires the watch (and returns true) if the provided value differs from
+// the value the watch was created with.
+func (w *watch) check(value []byte, present bool) bool {
+	if present != w.present || !bytes.Equal(value, w.value) {
+		w.fire(nil)
+		return true
+	}
+	return false
+}
+
+func (w *watch) future() fdb.FutureNil {
+	return fdb.NewFutureNil(w.ready, func() error {
+		return w.e
+	}, func() {
+		w.s.removeWatch(w)
+		w.fire(fdb.ErrOperationCancelled)
+	})
+}
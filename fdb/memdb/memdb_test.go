@@ -0,0 +1,792 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package memdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// newTestDatabase returns an in-memory database holding the provided keys
+// (each with itself as its value), and its store.
+func newTestDatabase(t *testing.T, keys ...string) (fdb.Database, *store) {
+	s := &store{history: make(map[string][]entry)}
+	db := fdb.NewDatabase(s)
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		for _, k := range keys {
+			tr.Set(fdb.Key(k), []byte(k))
+		}
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	return db, s
+}
+
+func TestGetKey(t *testing.T) {
+	db, _ := newTestDatabase(t, "a", "b", "c", "d")
+
+	tests := []struct {
+		sel fdb.KeySelector
+		key string
+	}{
+		{fdb.FirstGreaterOrEqual(fdb.Key("b")), "b"},
+		{fdb.FirstGreaterOrEqual(fdb.Key("bb")), "c"},
+		{fdb.FirstGreaterThan(fdb.Key("b")), "c"},
+		{fdb.LastLessOrEqual(fdb.Key("b")), "b"},
+		{fdb.LastLessOrEqual(fdb.Key("bb")), "b"},
+		{fdb.LastLessThan(fdb.Key("b")), "a"},
+		{fdb.KeySelector{Key: fdb.Key("a"), OrEqual: false, Offset: 3}, "c"},
+		{fdb.KeySelector{Key: fdb.Key("d"), OrEqual: true, Offset: -1}, "c"},
+		{fdb.LastLessThan(fdb.Key("a")), ""},
+		{fdb.FirstGreaterThan(fdb.Key("d")), "\xff"},
+		{fdb.KeySelector{Key: fdb.Key("b"), OrEqual: false, Offset: 10}, "\xff"},
+	}
+
+	for _, tt := range tests {
+		ret, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+			return rtr.GetKey(tt.sel).Get()
+		})
+		if e != nil {
+			t.Errorf("%v: %v", tt.sel, e)
+			continue
+		}
+		if k := string(ret.(fdb.Key)); k != tt.key {
+			t.Errorf("%v resolved to %q, expected %q", tt.sel, k, tt.key)
+		}
+	}
+}
+
+func TestGetKeyOwnWrites(t *testing.T) {
+	db, _ := newTestDatabase(t, "a", "b", "c", "d")
+
+	tests := []struct {
+		sel fdb.KeySelector
+		key string
+	}{
+		{fdb.FirstGreaterThan(fdb.Key("b")), "bb"},
+		{fdb.FirstGreaterOrEqual(fdb.Key("c")), "d"},
+		{fdb.LastLessThan(fdb.Key("d")), "bb"},
+		{fdb.KeySelector{Key: fdb.Key("a"), OrEqual: false, Offset: 4}, "d"},
+		{fdb.KeySelector{Key: fdb.Key("e"), OrEqual: false, Offset: -1}, "bb"},
+		{fdb.KeySelector{Key: fdb.Key("d"), OrEqual: true, Offset: -4}, ""},
+	}
+
+	for _, tt := range tests {
+		ret, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			/* Keys the transaction writes are found, and those it clears
+			/* are skipped */
+			tr.Set(fdb.Key("bb"), []byte("bb"))
+			tr.Clear(fdb.Key("c"))
+			return tr.GetKey(tt.sel).Get()
+		})
+		if e != nil {
+			t.Errorf("%v: %v", tt.sel, e)
+			continue
+		}
+		if k := string(ret.(fdb.Key)); k != tt.key {
+			t.Errorf("%v resolved to %q, expected %q", tt.sel, k, tt.key)
+		}
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	db, _ := newTestDatabase(t, "a", "b", "c", "d", "e")
+
+	all := fdb.KeyRange{Begin: fdb.Key(""), End: fdb.Key{0xFF}}
+
+	tests := []struct {
+		name string
+		r fdb.Range
+		options fdb.RangeOptions
+		keys []string
+	}{
+		{"all", all, fdb.RangeOptions{}, []string{"a", "b", "c", "d", "e"}},
+		{"limit", all, fdb.RangeOptions{Limit: 2}, []string{"a", "b"}},
+		{"reverse", all, fdb.RangeOptions{Reverse: true}, []string{"e", "d", "c", "b", "a"}},
+		{"reverse limit", all, fdb.RangeOptions{Reverse: true, Limit: 2}, []string{"e", "d"}},
+		{"limit beyond range", all, fdb.RangeOptions{Limit: 10}, []string{"a", "b", "c", "d", "e"}},
+		{"key range", fdb.KeyRange{Begin: fdb.Key("b"), End: fdb.Key("d")}, fdb.RangeOptions{}, []string{"b", "c"}},
+		{"reverse key range", fdb.KeyRange{Begin: fdb.Key("b"), End: fdb.Key("d")}, fdb.RangeOptions{Reverse: true}, []string{"c", "b"}},
+		{"selector range", fdb.SelectorRange{Begin: fdb.FirstGreaterThan(fdb.Key("a")), End: fdb.LastLessOrEqual(fdb.Key("d"))}, fdb.RangeOptions{}, []string{"b", "c"}},
+		{"empty range", fdb.KeyRange{Begin: fdb.Key("bb"), End: fdb.Key("bc")}, fdb.RangeOptions{}, nil},
+		{"inverted selectors", fdb.SelectorRange{Begin: fdb.FirstGreaterOrEqual(fdb.Key("d")), End: fdb.FirstGreaterOrEqual(fdb.Key("b"))}, fdb.RangeOptions{}, nil},
+		{"small batches", all, fdb.RangeOptions{Mode: fdb.StreamingModeSmall}, []string{"a", "b", "c", "d", "e"}},
+	}
+
+	for _, tt := range tests {
+		ret, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+			return rtr.GetRange(tt.r, tt.options).GetSliceWithError()
+		})
+		if e != nil {
+			t.Errorf("%s: %v", tt.name, e)
+			continue
+		}
+
+		kvs := ret.([]fdb.KeyValue)
+		var keys []string
+		for _, kv := range kvs {
+			keys = append(keys, string(kv.Key))
+			if string(kv.Value) != string(kv.Key) {
+				t.Errorf("%s: key %q has value %q", tt.name, kv.Key, kv.Value)
+			}
+		}
+		if len(keys) != len(tt.keys) {
+			t.Errorf("%s: read %q, expected %q", tt.name, keys, tt.keys)
+			continue
+		}
+		for i := range keys {
+			if keys[i] != tt.keys[i] {
+				t.Errorf("%s: read %q, expected %q", tt.name, keys, tt.keys)
+				break
+			}
+		}
+	}
+}
+
+// numberedKeys returns the keys k00 to k(n-1), each of which (with itself as
+// its value) is 6 bytes in size.
+func numberedKeys(n int) []string {
+	var keys []string
+	for i := 0; i < n; i++ {
+		keys = append(keys, fmt.Sprintf("k%02d", i))
+	}
+	return keys
+}
+
+func TestGetRangeTargetBytes(t *testing.T) {
+	keys := numberedKeys(50)
+	db, _ := newTestDatabase(t, keys...)
+
+	tests := []struct {
+		name string
+		options fdb.RangeOptions
+		n int
+	}{
+		{"small batches", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 200}, 34},
+		{"iterator batches", fdb.RangeOptions{TargetBytes: 200}, 34},
+		{"exact target", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 120}, 20},
+		{"first pair", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 1}, 1},
+		{"beyond range", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 1000}, 50},
+		{"reverse", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 200, Reverse: true}, 34},
+		{"limit first", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 200, Limit: 20}, 20},
+		{"target first", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 200, Limit: 40}, 34},
+		{"reverse limit first", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 200, Limit: 17, Reverse: true}, 17},
+		{"reverse target first", fdb.RangeOptions{Mode: fdb.StreamingModeSmall, TargetBytes: 200, Limit: 40, Reverse: true}, 34},
+	}
+
+	for _, tt := range tests {
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		var read []string
+		for kv, e := range tr.GetRange(fdb.KeyRange{Begin: fdb.Key("k"), End: fdb.Key("l")}, tt.options).All() {
+			if e != nil {
+				t.Fatalf("%s: %v", tt.name, e)
+			}
+			read = append(read, string(kv.Key))
+		}
+
+		if len(read) != tt.n {
+			t.Errorf("%s: read %d key-value pairs, expected %d", tt.name, len(read), tt.n)
+			continue
+		}
+		for i, k := range read {
+			expected := keys[i]
+			if tt.options.Reverse {
+				expected = keys[len(keys)-1-i]
+			}
+			if k != expected {
+				t.Errorf("%s: read %s at position %d, expected %s", tt.name, k, i, expected)
+				break
+			}
+		}
+	}
+}
+
+func TestGetRangeSplitPoints(t *testing.T) {
+	db, _ := newTestDatabase(t, numberedKeys(50)...)
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		tr.Clear(fdb.Key("k12"))
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tests := []struct {
+		begin, end string
+		chunkSize int64
+		points []string
+	}{
+		{"k", "l", 60, []string{"k", "k10", "k21", "k31", "k41", "l"}},
+		{"k05", "k25", 30, []string{"k05", "k10", "k16", "k21", "k25"}},
+		{"k05", "k25", 1, []string{"k05", "k06", "k07", "k08", "k09", "k10", "k11", "k13", "k14", "k15", "k16", "k17", "k18", "k19", "k20", "k21", "k22", "k23", "k24", "k25"}},
+		{"k", "l", 1000, []string{"k", "l"}},
+		{"a", "b", 60, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		ret, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+			return rtr.GetRangeSplitPoints(fdb.KeyRange{Begin: fdb.Key(tt.begin), End: fdb.Key(tt.end)}, tt.chunkSize).Get()
+		})
+		if e != nil {
+			t.Errorf("%s-%s: %v", tt.begin, tt.end, e)
+			continue
+		}
+
+		var points []string
+		for _, k := range ret.([]fdb.Key) {
+			points = append(points, string(k))
+		}
+		if fmt.Sprint(points) != fmt.Sprint(tt.points) {
+			t.Errorf("%s-%s split into chunks of %d bytes at %q, expected %q", tt.begin, tt.end, tt.chunkSize, points, tt.points)
+		}
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(tr fdb.Transaction) error
+		write bool
+		err error
+	}{
+		{"read overwritten", func(tr fdb.Transaction) error {
+			_, e := tr.Get(fdb.Key("k")).Get()
+			return e
+		}, true, fdb.ErrNotCommitted},
+		{"range read overwritten", func(tr fdb.Transaction) error {
+			_, e := tr.GetRange(fdb.KeyRange{Begin: fdb.Key("a"), End: fdb.Key("z")}, fdb.RangeOptions{}).GetSliceWithError()
+			return e
+		}, true, fdb.ErrNotCommitted},
+		{"read conflict key", func(tr fdb.Transaction) error {
+			tr.GetReadVersion().Get()
+			return tr.AddReadConflictKey(fdb.Key("k"))
+		}, true, fdb.ErrNotCommitted},
+		{"other key read", func(tr fdb.Transaction) error {
+			_, e := tr.Get(fdb.Key("j")).Get()
+			return e
+		}, true, nil},
+		{"snapshot read", func(tr fdb.Transaction) error {
+			_, e := tr.Snapshot().Get(fdb.Key("k")).Get()
+			return e
+		}, true, nil},
+		{"read-only", func(tr fdb.Transaction) error {
+			_, e := tr.Get(fdb.Key("k")).Get()
+			return e
+		}, false, nil},
+		{"write-only", func(tr fdb.Transaction) error {
+			return nil
+		}, true, nil},
+	}
+
+	for _, tt := range tests {
+		db, _ := newTestDatabase(t, "k")
+
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e := tt.read(tr); e != nil {
+			t.Errorf("%s: %v", tt.name, e)
+			continue
+		}
+
+		_, e = db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			tr.Set(fdb.Key("k"), []byte("changed"))
+			return nil, nil
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		if tt.write {
+			tr.Set(fdb.Key("w"), []byte("written"))
+		}
+		if e := tr.Commit().Get(); e != tt.err {
+			t.Errorf("%s: commit returned %v, expected %v", tt.name, e, tt.err)
+		}
+	}
+}
+
+func TestWatches(t *testing.T) {
+	db, s := newTestDatabase(t, "k")
+
+	watch := func(key string) fdb.FutureNil {
+		ret, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			return tr.Watch(fdb.Key(key)), nil
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		return ret.(fdb.FutureNil)
+	}
+	set := func(key, value string) {
+		_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			tr.Set(fdb.Key(key), []byte(value))
+			return nil, nil
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	w := watch("k")
+	unchanged := watch("k")
+	cancelled := watch("k")
+
+	/* Setting the value the watch was created with does not fire it */
+	set("k", "k")
+	if w.IsReady() {
+		t.Error("watch fired without a change")
+	}
+
+	cancelled.Cancel()
+	if e := cancelled.Get(); e != fdb.ErrOperationCancelled {
+		t.Errorf("cancelled watch returned %v, expected ErrOperationCancelled", e)
+	}
+
+	set("k", "changed")
+	for _, f := range []fdb.FutureNil{w, unchanged} {
+		if !f.IsReady() {
+			t.Error("watch not fired by a change")
+		}
+		if e := f.Get(); e != nil {
+			t.Error(e)
+		}
+	}
+
+	s.mu.Lock()
+	n := len(s.watches)
+	s.mu.Unlock()
+	if n != 0 {
+		t.Errorf("watches of %d keys still registered once fired", n)
+	}
+
+	/* A watch on an absent key fires when the key is set */
+	absent := watch("absent")
+	set("absent", "")
+	if !absent.IsReady() {
+		t.Error("watch of an absent key not fired when set")
+	}
+
+	/* A watch of a transaction that is not committed fails */
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+	failed := tr.Watch(fdb.Key("k"))
+	tr.Reset()
+	if e := failed.Get(); e != fdb.ErrTransactionCancelled {
+		t.Errorf("watch of a reset transaction returned %v, expected ErrTransactionCancelled", e)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	db, _ := newTestDatabase(t, "k")
+
+	get := func(tr fdb.Transaction) error {
+		_, e := tr.Get(fdb.Key("k")).Get()
+		return e
+	}
+
+	tests := []struct {
+		name string
+		run func(tr fdb.Transaction) error
+		err error
+	}{
+		{"elapsed", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10)
+			time.Sleep(20 * time.Millisecond)
+			return get(tr)
+		}, fdb.ErrTransactionTimedOut},
+		{"not elapsed", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10000)
+			return get(tr)
+		}, nil},
+		{"measured from transaction start", func(tr fdb.Transaction) error {
+			time.Sleep(20 * time.Millisecond)
+			tr.Options().SetTimeout(10)
+			return get(tr)
+		}, fdb.ErrTransactionTimedOut},
+		{"disabled", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10)
+			tr.Options().SetTimeout(0)
+			time.Sleep(20 * time.Millisecond)
+			return get(tr)
+		}, nil},
+		{"kept by OnError", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10)
+			if e := tr.OnError(fdb.ErrNotCommitted).Get(); e != nil {
+				return e
+			}
+			time.Sleep(20 * time.Millisecond)
+			return get(tr)
+		}, fdb.ErrTransactionTimedOut},
+		{"OnError after elapsed", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10)
+			time.Sleep(20 * time.Millisecond)
+			return tr.OnError(fdb.ErrNotCommitted).Get()
+		}, fdb.ErrTransactionTimedOut},
+		{"cleared by Reset", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10)
+			time.Sleep(20 * time.Millisecond)
+			tr.Reset()
+			return get(tr)
+		}, nil},
+		{"commit", func(tr fdb.Transaction) error {
+			tr.Options().SetTimeout(10)
+			tr.Set(fdb.Key("k"), []byte("v"))
+			time.Sleep(20 * time.Millisecond)
+			return tr.Commit().Get()
+		}, fdb.ErrTransactionTimedOut},
+	}
+
+	for _, tt := range tests {
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e := tt.run(tr); e != tt.err {
+			t.Errorf("%s: returned %v, expected %v", tt.name, e, tt.err)
+		}
+	}
+}
+
+// selectAPIVersion makes the in-memory database emulate version for the
+// duration of a test.
+func selectAPIVersion(t *testing.T, version int) {
+	old := getAPIVersion
+	getAPIVersion = func() (int, error) { return version, nil }
+	t.Cleanup(func() { getAPIVersion = old })
+}
+
+func TestOnErrorOptions(t *testing.T) {
+	db, _ := newTestDatabase(t, "k")
+
+	tests := []struct {
+		version int
+		kept bool
+	}{
+		{200, false},
+		{520, false},
+		{610, true},
+		{710, true},
+	}
+
+	for _, tt := range tests {
+		selectAPIVersion(t, tt.version)
+
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+		tr.Options().SetTimeout(10)
+		tr.Options().SetRetryLimit(1)
+
+		if e := tr.OnError(fdb.ErrNotCommitted).Get(); e != nil {
+			t.Fatalf("API version %d: first OnError returned %v", tt.version, e)
+		}
+
+		/* The retry limit of 1 is used up by the first OnError */
+		e = tr.OnError(fdb.ErrNotCommitted).Get()
+		if tt.kept && e != fdb.ErrNotCommitted {
+			t.Errorf("API version %d: OnError beyond the retry limit returned %v, expected ErrNotCommitted", tt.version, e)
+		}
+		if !tt.kept && e != nil {
+			t.Errorf("API version %d: OnError after the retry limit was cleared returned %v", tt.version, e)
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		_, e = tr.Get(fdb.Key("k")).Get()
+		if tt.kept && e != fdb.ErrTransactionTimedOut {
+			t.Errorf("API version %d: read after the timeout returned %v, expected ErrTransactionTimedOut", tt.version, e)
+		}
+		if !tt.kept && e != nil {
+			t.Errorf("API version %d: read after the timeout was cleared returned %v", tt.version, e)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	db, _ := newTestDatabase(t, "k")
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tr.Cancel()
+	if _, e := tr.Get(fdb.Key("k")).Get(); e != fdb.ErrTransactionCancelled {
+		t.Errorf("read of a cancelled transaction returned %v", e)
+	}
+
+	/* OnError does not revive a cancelled transaction */
+	if e := tr.OnError(fdb.ErrNotCommitted).Get(); !errors.Is(e, fdb.ErrTransactionCancelled) {
+		t.Errorf("OnError of a cancelled transaction returned %v", e)
+	}
+	if _, e := tr.Get(fdb.Key("k")).Get(); e != fdb.ErrTransactionCancelled {
+		t.Errorf("read of a cancelled transaction after OnError returned %v", e)
+	}
+
+	tr.Reset()
+	if _, e := tr.Get(fdb.Key("k")).Get(); e != nil {
+		t.Errorf("read of a reset transaction returned %v", e)
+	}
+}
+
+func TestCommitTwice(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tr.Add(fdb.Key("n"), []byte{1})
+	if e := tr.Commit().Get(); e != nil {
+		t.Fatal(e)
+	}
+	if e := tr.Commit().Get(); e != fdb.ErrUsedDuringCommit {
+		t.Errorf("second commit returned %v", e)
+	}
+
+	v, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+		return rtr.Get(fdb.Key("n")).Get()
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !bytes.Equal(v.([]byte), []byte{1}) {
+		t.Errorf("after committing twice, value is %v", v)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	db, s := newTestDatabase(t, "k")
+
+	/* A transaction still reading keeps the versions it may read */
+	reader, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if _, e := reader.Get(fdb.Key("k")).Get(); e != nil {
+		t.Fatal(e)
+	}
+	rv := reader.GetReadVersion().MustGet()
+
+	for i := 0; i < 10; i++ {
+		_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			tr.Set(fdb.Key("k"), []byte{byte(i)})
+			tr.Set(fdb.Key(fmt.Sprintf("t%d", i)), nil)
+			tr.Clear(fdb.Key(fmt.Sprintf("t%d", i)))
+			return nil, nil
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if v, e := reader.Get(fdb.Key("k")).Get(); e != nil || string(v) != "k" {
+		t.Errorf("read at the retained version returned %q, %v", v, e)
+	}
+	if n := len(s.history["k"]); n != 11 {
+		t.Errorf("key has %d entries while its versions are read, expected 11", n)
+	}
+
+	/* Once it is committed, they are discarded by later writes */
+	if e := reader.Commit().Get(); e != nil {
+		t.Fatal(e)
+	}
+	for i := 0; i < 4; i++ {
+		_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+			tr.Set(fdb.Key("k"), []byte("k"))
+			return nil, nil
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if n := len(s.history["k"]); n > 4 {
+		t.Errorf("key has %d entries after pruning", n)
+	}
+	if len(s.keys) != 1 {
+		t.Errorf("store holds keys %q after pruning, expected only the key present", s.keys)
+	}
+	if len(s.commits) > 4 {
+		t.Errorf("store holds %d commits after pruning", len(s.commits))
+	}
+
+	old, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+	old.SetReadVersion(rv)
+	if _, e := old.Get(fdb.Key("k")).Get(); e != fdb.ErrTransactionTooOld {
+		t.Errorf("read at a discarded version returned %v", e)
+	}
+}
+
+// commitStamped commits a transaction performing mutate, returning its
+// versionstamp.
+func commitStamped(t *testing.T, db fdb.Database, mutate func(tr fdb.Transaction)) fdb.Key {
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	mutate(tr)
+	vs := tr.GetVersionstamp()
+	if e := tr.Commit().Get(); e != nil {
+		t.Fatal(e)
+	}
+
+	stamp, e := vs.Get()
+	if e != nil {
+		t.Fatal(e)
+	}
+	version, e := tr.GetCommittedVersion()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if expected := stampOf(version); !bytes.Equal(stamp, expected) {
+		t.Errorf("versionstamp %x, expected %x for committed version %d", stamp, expected, version)
+	}
+
+	return stamp
+}
+
+func TestVersionstampedKey(t *testing.T) {
+	db, _ := newTestDatabase(t)
+	prefix := tuple.Tuple{"stamped"}
+
+	var stamps []fdb.Key
+	for i := 0; i < 2; i++ {
+		key, e := append(prefix, tuple.IncompleteVersionstamp(7)).PackWithVersionstamp(nil)
+		if e != nil {
+			t.Fatal(e)
+		}
+		stamps = append(stamps, commitStamped(t, db, func(tr fdb.Transaction) {
+			tr.SetVersionstampedKey(fdb.Key(key), []byte("value"))
+		}))
+	}
+	if bytes.Compare(stamps[0], stamps[1]) >= 0 {
+		t.Errorf("versionstamps %x and %x are not increasing", stamps[0], stamps[1])
+	}
+
+	kvs, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+		return rtr.GetRange(prefix, fdb.RangeOptions{}).GetSliceWithError()
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var keys []tuple.Tuple
+	for _, kv := range kvs.([]fdb.KeyValue) {
+		tup, e := tuple.Unpack(kv.Key)
+		if e != nil {
+			t.Fatal(e)
+		}
+		keys = append(keys, tup)
+	}
+	if len(keys) != len(stamps) {
+		t.Fatalf("read keys %v, expected %d", keys, len(stamps))
+	}
+	for i, k := range keys {
+		var expected tuple.Versionstamp
+		copy(expected.TransactionVersion[:], stamps[i])
+		expected.UserVersion = 7
+		if len(k) != 2 || k[1] != expected {
+			t.Errorf("read key %v, expected (%q, %v)", k, prefix[0], expected)
+		}
+	}
+
+	/* Before API version 520, the offset is 2 bytes */
+	selectAPIVersion(t, 510)
+	stamp := commitStamped(t, db, func(tr fdb.Transaction) {
+		key := append(append([]byte("old"), make([]byte, 10)...), 0x03, 0x00)
+		tr.SetVersionstampedKey(fdb.Key(key), []byte("value"))
+	})
+	v, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+		return rtr.Get(fdb.Key(append([]byte("old"), stamp...))).Get()
+	})
+	if e != nil || !bytes.Equal(v.([]byte), []byte("value")) {
+		t.Errorf("read %q (%v) from the key stamped at API version 510", v, e)
+	}
+}
+
+func TestVersionstampedValue(t *testing.T) {
+	db, _ := newTestDatabase(t)
+
+	value, e := tuple.Tuple{"a", tuple.IncompleteVersionstamp(3)}.PackWithVersionstamp(nil)
+	if e != nil {
+		t.Fatal(e)
+	}
+	stamp := commitStamped(t, db, func(tr fdb.Transaction) {
+		tr.SetVersionstampedValue(fdb.Key("k"), value)
+	})
+
+	get := func() []byte {
+		v, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+			return rtr.Get(fdb.Key("k")).Get()
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		return v.([]byte)
+	}
+
+	tup, e := tuple.Unpack(get())
+	if e != nil {
+		t.Fatal(e)
+	}
+	var expected tuple.Versionstamp
+	copy(expected.TransactionVersion[:], stamp)
+	expected.UserVersion = 3
+	if len(tup) != 2 || tup[0] != "a" || tup[1] != expected {
+		t.Errorf("read value %v, expected (\"a\", %v)", tup, expected)
+	}
+
+	/* Before API version 520, the value is stamped at its beginning */
+	selectAPIVersion(t, 510)
+	stamp = commitStamped(t, db, func(tr fdb.Transaction) {
+		tr.SetVersionstampedValue(fdb.Key("k"), append(make([]byte, 10), "suffix"...))
+	})
+	if v, expected := get(), append(append([]byte{}, stamp...), "suffix"...); !bytes.Equal(v, expected) {
+		t.Errorf("read value %x stamped at API version 510, expected %x", v, expected)
+	}
+}
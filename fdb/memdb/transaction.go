@@ -0,0 +1,1011 @@
+// FoundationDB Go In-Memory Database
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package memdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+)
+
+type mutationType int
+
+const (
+	mutationSet mutationType = iota
+	mutationClear
+	mutationClearRange
+	mutationAtomic
+	mutationVersionstamped
+)
+
+// Atomic operation codes, as passed to (fdb.TransactionBackend).AtomicOp.
+const (
+	opAdd = 2
+	opBitAnd = 6
+	opBitOr = 7
+	opBitXor = 8
+	opSetVersionstampedKey = 14
+	opSetVersionstampedValue = 15
+)
+
+type mutation struct {
+	t mutationType
+	key, end []byte
+	param []byte
+	code int
+}
+
+// apply returns the value of key after applying the mutation to a key whose
+// value was v (or which was not present, if ok is false).
+func (m mutation) apply(key string, v []byte, ok bool) ([]byte, bool) {
+	switch m.t {
+	case mutationVersionstamped:
+		// The effect of a versionstamped mutation is unknown until commit.
+		return v, ok
+	case mutationClearRange:
+		if key >= string(m.key) && key < string(m.end) {
+			return nil, false
+		}
+		return v, ok
+	}
+
+	if key != string(m.key) {
+		return v, ok
+	}
+
+	switch m.t {
+	case mutationSet:
+		return m.param, true
+	case mutationClear:
+		return nil, false
+	}
+
+	// Atomic operations treat the existing value as if it were zero-extended or
+	// truncated to the length of the parameter.
+	r := make([]byte, len(m.param))
+	copy(r, v)
+
+	switch m.code {
+	case opAdd:
+		carry := 0
+		for i := range r {
+			sum := int(r[i]) + int(m.param[i]) + carry
+			r[i] = byte(sum)
+			carry = sum >> 8
+		}
+	case opBitAnd:
+		for i := range r {
+			r[i] &= m.param[i]
+		}
+	case opBitOr:
+		for i := range r {
+			r[i] |= m.param[i]
+		}
+	case opBitXor:
+		for i := range r {
+			r[i] ^= m.param[i]
+		}
+	}
+
+	return r, true
+}
+
+// stamp returns the set mutation performed by a versionstamped mutation
+// committed with the versionstamp vs, or false if the offset of the
+// versionstamp within the operand of the mutation is out of range. The operand
+// ends with an offset of offsetSize bytes (or, for versionstamped values before
+// API version 520, has no offset and is stamped at its beginning).
+func (m mutation) stamp(vs []byte, offsetSize int) (mutation, bool) {
+	operand := m.param
+	if m.code == opSetVersionstampedKey {
+		operand = m.key
+	}
+
+	var pos int
+	switch {
+	case m.code == opSetVersionstampedValue && offsetSize == 2:
+	case len(operand) < offsetSize:
+		return mutation{}, false
+	case offsetSize == 2:
+		pos = int(binary.LittleEndian.Uint16(operand[len(operand)-2:]))
+		operand = operand[:len(operand)-2]
+	default:
+		pos = int(binary.LittleEndian.Uint32(operand[len(operand)-4:]))
+		operand = operand[:len(operand)-4]
+	}
+	if pos + len(vs) > len(operand) {
+		return mutation{}, false
+	}
+
+	stamped := append([]byte{}, operand...)
+	copy(stamped[pos:], vs)
+
+	if m.code == opSetVersionstampedKey {
+		return mutation{t: mutationSet, key: stamped, param: m.param}, true
+	}
+	return mutation{t: mutationSet, key: m.key, param: stamped}, true
+}
+
+// getAPIVersion returns the selected API version, whose behavior the in-memory
+// database emulates. It is replaced by tests to emulate older API versions.
+var getAPIVersion = fdb.GetAPIVersion
+
+// versionstampOffsetSize returns the size of the offset ending the operand of
+// a versionstamped mutation, which is 4 bytes unless an API version before 520
+// has been selected.
+func versionstampOffsetSize() int {
+	if v, e := getAPIVersion(); e == nil && v < 520 {
+		return 2
+	}
+	return 4
+}
+
+// versionstamp is the result of (fdb.Transaction).GetVersionstamp, which
+// becomes ready when the transaction commits (or fails to).
+type versionstamp struct {
+	ready chan struct{}
+	once sync.Once
+	v fdb.Key
+	e error
+}
+
+func (vs *versionstamp) fire(v fdb.Key, e error) {
+	vs.once.Do(func() {
+		vs.v, vs.e = v, e
+		close(vs.ready)
+	})
+}
+
+type transaction struct {
+	s *store
+	mu sync.Mutex
+
+	readVersion int64
+	hasReadVersion bool
+	committedVersion int64
+
+	mutations []mutation
+	reads, writes []keyRange
+	watches []*watch
+	stamp *versionstamp
+	commitErr error
+	cancelled bool
+	committed bool
+
+	// Whether the read version of the transaction is registered with the
+	// store, which retains the versions that it may read.
+	reading bool
+
+	// The timeout of the transaction is measured from when it was created or
+	// last reset by Reset.
+	started time.Time
+	timeout time.Duration
+	retryLimit int64
+	retries int64
+}
+
+func newTransaction(s *store) *transaction {
+	t := &transaction{s: s, committedVersion: -1, started: time.Now(), retryLimit: -1}
+	runtime.SetFinalizer(t, (*transaction).finalize)
+	return t
+}
+
+// finalize releases the read version of a transaction that is no longer
+// referenced, as a transaction that is neither committed nor reset would
+// otherwise retain the versions it may read forever.
+func (t *transaction) finalize() {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	t.release()
+}
+
+// release releases the read version of the transaction, if it is registered
+// with the store. The transaction and the store must be locked.
+func (t *transaction) release() {
+	if t.reading {
+		t.s.release(t.readVersion)
+		t.reading = false
+	}
+}
+
+func keyAfter(key []byte) []byte {
+	return append(append([]byte(nil), key...), 0x00)
+}
+
+func dup(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// check returns the error that operations on the transaction should fail
+// with, if any. The transaction must be locked.
+func (t *transaction) check() error {
+	if t.cancelled {
+		return fdb.ErrTransactionCancelled
+	}
+	if t.timeout > 0 && time.Since(t.started) > t.timeout {
+		return fdb.ErrTransactionTimedOut
+	}
+	return nil
+}
+
+// version returns the read version of the transaction, obtaining one if
+// necessary. The transaction and the store must be locked.
+func (t *transaction) version() (int64, error) {
+	if !t.hasReadVersion {
+		t.readVersion = t.s.version
+		t.hasReadVersion = true
+	}
+	if t.readVersion > t.s.version {
+		return 0, fdb.ErrFutureVersion
+	}
+	if t.readVersion < t.s.pruned {
+		return 0, fdb.ErrTransactionTooOld
+	}
+	if !t.reading && !t.cancelled && !t.committed {
+		t.s.acquire(t.readVersion)
+		t.reading = true
+	}
+	return t.readVersion, nil
+}
+
+// value returns the value of key as seen by the transaction, including its own
+// writes. The transaction and the store must be locked.
+func (t *transaction) value(key string, rv int64) ([]byte, bool) {
+	v, ok := t.s.get(key, rv)
+	for _, m := range t.mutations {
+		v, ok = m.apply(key, v, ok)
+	}
+	return v, ok
+}
+
+// localKeys returns the keys written by the set and atomic mutations of the
+// transaction, in order and without duplicates. The transaction must be locked.
+func (t *transaction) localKeys() []string {
+	var keys []string
+	for _, m := range t.mutations {
+		if m.t == mutationSet || m.t == mutationAtomic {
+			keys = append(keys, string(m.key))
+		}
+	}
+	sort.Strings(keys)
+
+	n := 0
+	for i, k := range keys {
+		if i == 0 || k != keys[n-1] {
+			keys[n] = k
+			n++
+		}
+	}
+	return keys[:n]
+}
+
+// A cursor moves through the keys present as seen by a transaction at a read
+// version, including its own writes, merging the keys ever written to the
+// store with those written by the transaction. Keys following the position of
+// the cursor are returned by next, and keys preceding it by prev.
+type cursor struct {
+	t *transaction
+	rv int64
+	stored, local []string
+	i, j int
+}
+
+// seek returns a cursor positioned before key. The transaction and the store
+// must be locked, and remain so while the cursor is used.
+func (t *transaction) seek(key string, rv int64) *cursor {
+	c := &cursor{t: t, rv: rv, stored: t.s.keys, local: t.localKeys()}
+	c.i = sort.SearchStrings(c.stored, key)
+	c.j = sort.SearchStrings(c.local, key)
+	return c
+}
+
+// next returns the first key present after the position of the cursor, and
+// moves the cursor past it.
+func (c *cursor) next() (string, bool) {
+	for {
+		var k string
+		si, li := c.i < len(c.stored), c.j < len(c.local)
+		switch {
+		case si && li && c.stored[c.i] == c.local[c.j]:
+			k = c.stored[c.i]
+			c.i++
+			c.j++
+		case si && (!li || c.stored[c.i] < c.local[c.j]):
+			k = c.stored[c.i]
+			c.i++
+		case li:
+			k = c.local[c.j]
+			c.j++
+		default:
+			return "", false
+		}
+
+		if _, ok := c.t.value(k, c.rv); ok {
+			return k, true
+		}
+	}
+}
+
+// prev returns the last key present before the position of the cursor, and
+// moves the cursor before it.
+func (c *cursor) prev() (string, bool) {
+	for {
+		var k string
+		si, li := c.i > 0, c.j > 0
+		switch {
+		case si && li && c.stored[c.i-1] == c.local[c.j-1]:
+			k = c.stored[c.i-1]
+			c.i--
+			c.j--
+		case si && (!li || c.stored[c.i-1] > c.local[c.j-1]):
+			k = c.stored[c.i-1]
+			c.i--
+		case li:
+			k = c.local[c.j-1]
+			c.j--
+		default:
+			return "", false
+		}
+
+		if _, ok := c.t.value(k, c.rv); ok {
+			return k, true
+		}
+	}
+}
+
+// resolve returns the key described by a key selector. Selectors resolving
+// before the first key in the database resolve to the empty key, and those
+// resolving after the last key resolve to "\xff". The transaction and the
+// store must be locked.
+func (t *transaction) resolve(sel fdb.KeySelector, rv int64) []byte {
+	k := string(sel.Key.FDBKey())
+	if sel.OrEqual {
+		k += "\x00"
+	}
+	c := t.seek(k, rv)
+
+	var key string
+	var ok bool
+	if sel.Offset > 0 {
+		for n := 0; n < sel.Offset; n++ {
+			if key, ok = c.next(); !ok {
+				return []byte{0xFF}
+			}
+		}
+	} else {
+		for n := sel.Offset; n <= 0; n++ {
+			if key, ok = c.prev(); !ok {
+				return []byte{}
+			}
+		}
+	}
+	return []byte(key)
+}
+
+// replyBytes limits the size of each batch of a range read, however it is
+// read, as the storage servers of a cluster limit the size of their replies.
+const replyBytes = 80000
+
+// batchRows returns the number of rows returned by each batch of a range read
+// in the given streaming mode, or 0 if only their size is limited.
+func batchRows(mode fdb.StreamingMode, iteration int) int {
+	switch mode {
+	case fdb.StreamingModeIterator:
+		if iteration > 8 {
+			iteration = 8
+		}
+		if iteration < 1 {
+			iteration = 1
+		}
+		return 16 << (iteration - 1)
+	case fdb.StreamingModeSmall:
+		return 16
+	case fdb.StreamingModeMedium:
+		return 256
+	case fdb.StreamingModeLarge:
+		return 4096
+	case fdb.StreamingModeSerial:
+		return 16384
+	}
+	return 0
+}
+
+func errorFutureNil(e error) fdb.FutureNil {
+	return fdb.NewFutureNil(nil, func() error { return e }, nil)
+}
+
+func (t *transaction) Get(key fdb.Key, snapshot bool) fdb.FutureByteSlice {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+	if e != nil {
+		return fdb.NewFutureByteSlice(nil, func() ([]byte, error) { return nil, e }, nil)
+	}
+
+	v, ok := t.value(string(key), rv)
+	if !snapshot {
+		t.reads = append(t.reads, keyRange{dup(key), keyAfter(key)})
+	}
+	if ok {
+		v = append([]byte{}, v...)
+	}
+
+	return fdb.NewFutureByteSlice(nil, func() ([]byte, error) { return v, nil }, nil)
+}
+
+func (t *transaction) GetKey(sel fdb.KeySelector, snapshot bool) fdb.FutureKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+	if e != nil {
+		return fdb.NewFutureKey(nil, func() (fdb.Key, error) { return nil, e }, nil)
+	}
+
+	k := t.resolve(sel, rv)
+	if !snapshot {
+		sk := sel.Key.FDBKey()
+		if bytes.Compare(k, sk) < 0 {
+			t.reads = append(t.reads, keyRange{k, keyAfter(sk)})
+		} else {
+			t.reads = append(t.reads, keyRange{dup(sk), keyAfter(k)})
+		}
+	}
+
+	return fdb.NewFutureKey(nil, func() (fdb.Key, error) { return k, nil }, nil)
+}
+
+func (t *transaction) GetRange(begin, end fdb.KeySelector, options fdb.RangeOptions, snapshot bool, iteration int) fdb.FutureKeyValueArray {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+	if e != nil {
+		return fdb.NewFutureKeyValueArray(nil, func() ([]fdb.KeyValue, bool, error) { return nil, false, e }, nil)
+	}
+
+	b := t.resolve(begin, rv)
+	en := t.resolve(end, rv)
+	if bytes.Compare(b, en) >= 0 {
+		return fdb.NewFutureKeyValueArray(nil, func() ([]fdb.KeyValue, bool, error) { return nil, false, nil }, nil)
+	}
+
+	/* Only the keys returned (and one more, to tell whether more remain)
+	/* are visited */
+	n := options.Limit
+	if rows := batchRows(options.Mode, iteration); rows > 0 && (n == 0 || rows < n) {
+		n = rows
+	}
+	target := options.TargetBytes
+	if target <= 0 || target > replyBytes {
+		target = replyBytes
+	}
+
+	var c *cursor
+	step := func() (string, bool) {
+		if options.Reverse {
+			k, ok := c.prev()
+			return k, ok && k >= string(b)
+		}
+		k, ok := c.next()
+		return k, ok && k < string(en)
+	}
+	if options.Reverse {
+		c = t.seek(string(en), rv)
+	} else {
+		c = t.seek(string(b), rv)
+	}
+
+	var keys []string
+	var size int
+	more := false
+	for {
+		k, ok := step()
+		if !ok {
+			break
+		}
+		if n > 0 && len(keys) == n || size >= target {
+			more = true
+			break
+		}
+		keys = append(keys, k)
+		v, _ := t.value(k, rv)
+		size += len(k) + len(v)
+	}
+	n = len(keys)
+
+	kvs := make([]fdb.KeyValue, n)
+	for i, k := range keys {
+		v, _ := t.value(k, rv)
+		kvs[i] = fdb.KeyValue{Key: fdb.Key(k), Value: append([]byte{}, v...)}
+	}
+
+	if !snapshot {
+		r := keyRange{b, en}
+		if more {
+			if options.Reverse {
+				r.begin = []byte(keys[n-1])
+			} else {
+				r.end = keyAfter([]byte(keys[n-1]))
+			}
+		}
+		t.reads = append(t.reads, r)
+	}
+
+	return fdb.NewFutureKeyValueArray(nil, func() ([]fdb.KeyValue, bool, error) { return kvs, more, nil }, nil)
+}
+
+func (t *transaction) GetReadVersion() fdb.FutureInt64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+
+	return fdb.NewFutureInt64(nil, func() (int64, error) { return rv, e }, nil)
+}
+
+func (t *transaction) SetReadVersion(version int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	t.release()
+	t.readVersion = version
+	t.hasReadVersion = true
+}
+
+func (t *transaction) GetAddressesForKey(key fdb.Key) fdb.FutureStringSlice {
+	return fdb.NewFutureStringSlice(nil, func() ([]string, error) { return nil, nil }, nil)
+}
+
+func (t *transaction) GetEstimatedRangeSizeBytes(begin, end fdb.Key) fdb.FutureInt64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+
+	var size int64
+	if e == nil {
+		for _, k := range t.s.keysBetween(begin, end) {
+			if v, ok := t.s.get(k, rv); ok {
+				size += int64(len(k) + len(v))
+			}
+		}
+	}
+
+	return fdb.NewFutureInt64(nil, func() (int64, error) { return size, e }, nil)
+}
+
+func (t *transaction) GetRangeSplitPoints(begin, end fdb.Key, chunkSize int64) fdb.FutureKeyArray {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+
+	var points []fdb.Key
+	if e == nil {
+		points = append(points, append(fdb.Key{}, begin...))
+
+		var size int64
+		for _, k := range t.s.keysBetween(begin, end) {
+			v, ok := t.s.get(k, rv)
+			if !ok {
+				continue
+			}
+			if size >= chunkSize && k != string(begin) {
+				points = append(points, fdb.Key(k))
+				size = 0
+			}
+			size += int64(len(k) + len(v))
+		}
+
+		points = append(points, append(fdb.Key{}, end...))
+	}
+
+	return fdb.NewFutureKeyArray(nil, func() ([]fdb.Key, error) { return points, e }, nil)
+}
+
+func (t *transaction) GetApproximateSize() fdb.FutureInt64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var size int64
+	for _, m := range t.mutations {
+		size += int64(len(m.key) + len(m.end) + len(m.param))
+	}
+	for _, r := range append(t.reads, t.writes...) {
+		size += int64(len(r.begin) + len(r.end))
+	}
+
+	return fdb.NewFutureInt64(nil, func() (int64, error) { return size, nil }, nil)
+}
+
+func (t *transaction) mutate(m mutation, write keyRange) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.mutations = append(t.mutations, m)
+	t.writes = append(t.writes, write)
+}
+
+func (t *transaction) Set(key fdb.Key, value []byte) {
+	t.mutate(mutation{t: mutationSet, key: dup(key), param: append([]byte{}, value...)}, keyRange{dup(key), keyAfter(key)})
+}
+
+func (t *transaction) Clear(key fdb.Key) {
+	t.mutate(mutation{t: mutationClear, key: dup(key)}, keyRange{dup(key), keyAfter(key)})
+}
+
+func (t *transaction) ClearRange(begin, end fdb.Key) {
+	t.mutate(mutation{t: mutationClearRange, key: dup(begin), end: dup(end)}, keyRange{dup(begin), dup(end)})
+}
+
+func (t *transaction) AtomicOp(key fdb.Key, param []byte, code int) {
+	switch code {
+	case opAdd, opBitAnd, opBitOr, opBitXor:
+	case opSetVersionstampedKey:
+		// The key written, and so the write conflict range, is known only
+		// at commit.
+		t.mu.Lock()
+		t.mutations = append(t.mutations, mutation{t: mutationVersionstamped, key: dup(key), param: append([]byte{}, param...), code: code})
+		t.mu.Unlock()
+		return
+	case opSetVersionstampedValue:
+		t.mutate(mutation{t: mutationVersionstamped, key: dup(key), param: append([]byte{}, param...), code: code}, keyRange{dup(key), keyAfter(key)})
+		return
+	default:
+		t.mu.Lock()
+		if t.commitErr == nil {
+			t.commitErr = fdb.ErrInvalidMutationType
+		}
+		t.mu.Unlock()
+		return
+	}
+
+	t.mutate(mutation{t: mutationAtomic, key: dup(key), param: append([]byte{}, param...), code: code}, keyRange{dup(key), keyAfter(key)})
+}
+
+func (t *transaction) AddConflictRange(begin, end fdb.Key, write bool) error {
+	if bytes.Compare(begin, end) > 0 {
+		return fdb.ErrInvertedRange
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := keyRange{dup(begin), dup(end)}
+	if write {
+		t.writes = append(t.writes, r)
+	} else {
+		t.reads = append(t.reads, r)
+	}
+
+	return nil
+}
+
+func (t *transaction) Watch(key fdb.Key) fdb.FutureNil {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	rv, e := t.version()
+	if e == nil {
+		e = t.check()
+	}
+	if e != nil {
+		return errorFutureNil(e)
+	}
+
+	v, ok := t.value(string(key), rv)
+	w := &watch{s: t.s, key: string(key), value: v, present: ok, ready: make(chan struct{})}
+	t.watches = append(t.watches, w)
+
+	return w.future()
+}
+
+func (t *transaction) Commit() fdb.FutureNil {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	/* A read-only transaction commits trivially, whatever it has read */
+	readOnly := len(t.mutations) == 0 && len(t.writes) == 0
+
+	e := t.check()
+	if e == nil && t.committed {
+		return errorFutureNil(fdb.ErrUsedDuringCommit)
+	}
+	if e == nil {
+		e = t.commitErr
+	}
+	if e == nil && !readOnly && t.hasReadVersion && t.s.conflicts(t.readVersion, t.reads) {
+		e = fdb.ErrNotCommitted
+	}
+	if e == nil {
+		e = t.stampMutations()
+	}
+	if e != nil {
+		t.failWatches(e)
+		t.versionstamp().fire(nil, e)
+		return errorFutureNil(e)
+	}
+
+	if readOnly {
+		t.committedVersion = -1
+		t.versionstamp().fire(nil, fdb.ErrNoCommitVersion)
+	} else {
+		t.apply()
+		t.committedVersion = t.s.version
+		t.versionstamp().fire(stampOf(t.s.version), nil)
+	}
+
+	for _, w := range t.watches {
+		t.s.addWatch(w)
+	}
+	t.watches = nil
+
+	/* The versions the transaction may read are no longer retained */
+	t.committed = true
+	t.release()
+
+	return errorFutureNil(nil)
+}
+
+// stampOf returns the versionstamp of the transaction committed at version. As
+// each version of the in-memory database is committed by a single transaction,
+// the order of the transaction within its version is always zero.
+func stampOf(version int64) fdb.Key {
+	vs := make([]byte, 10)
+	binary.BigEndian.PutUint64(vs, uint64(version))
+	return vs
+}
+
+// stampMutations replaces the versionstamped mutations of the transaction with
+// the set mutations they perform when committed at the next version, returning
+// fdb.ErrClientInvalidOperation if the offset of any versionstamp is out of
+// range. The transaction and the store must be locked.
+func (t *transaction) stampMutations() error {
+	vs := stampOf(t.s.version + 1)
+	offsetSize := versionstampOffsetSize()
+
+	for i, m := range t.mutations {
+		if m.t != mutationVersionstamped {
+			continue
+		}
+		sm, ok := m.stamp(vs, offsetSize)
+		if !ok {
+			return fdb.ErrClientInvalidOperation
+		}
+		t.mutations[i] = sm
+		if m.code == opSetVersionstampedKey {
+			t.writes = append(t.writes, keyRange{sm.key, keyAfter(sm.key)})
+		}
+	}
+
+	return nil
+}
+
+// versionstamp returns the versionstamp of the transaction, which becomes
+// ready when the transaction commits. The transaction must be locked.
+func (t *transaction) versionstamp() *versionstamp {
+	if t.stamp == nil {
+		t.stamp = &versionstamp{ready: make(chan struct{})}
+	}
+	return t.stamp
+}
+
+func (t *transaction) GetVersionstamp() fdb.FutureKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	vs := t.versionstamp()
+	return fdb.NewFutureKey(vs.ready, func() (fdb.Key, error) {
+		return vs.v, vs.e
+	}, nil)
+}
+
+// apply commits the mutations of the transaction at a new version. The
+// transaction and the store must be locked.
+func (t *transaction) apply() {
+	s := t.s
+	prev := s.version
+	s.version++
+
+	affected := make(map[string]bool)
+	for _, m := range t.mutations {
+		if m.t == mutationClearRange {
+			for _, k := range s.keysBetween(m.key, m.end) {
+				affected[k] = true
+			}
+		} else {
+			affected[string(m.key)] = true
+		}
+	}
+
+	keys := make([]string, 0, len(affected))
+	for k := range affected {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		base, present := s.get(k, prev)
+		v, ok := base, present
+		for _, m := range t.mutations {
+			v, ok = m.apply(k, v, ok)
+		}
+		if ok || present {
+			s.write(k, v, ok)
+		}
+	}
+
+	s.commits = append(s.commits, commitRecord{s.version, t.writes})
+	s.written += len(keys) + 1
+	s.prune()
+}
+
+func (t *transaction) GetCommittedVersion() (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.committedVersion, nil
+}
+
+// failWatches causes any watches created by the transaction, but not yet
+// registered by a successful commit, to fail with the provided error. The
+// transaction must be locked.
+func (t *transaction) failWatches(e error) {
+	for _, w := range t.watches {
+		w.fire(e)
+	}
+	t.watches = nil
+}
+
+// reset returns the transaction to its initial state, retaining its options, as
+// after a retryable error. The transaction must be locked.
+func (t *transaction) reset() {
+	t.failWatches(fdb.ErrTransactionCancelled)
+	if t.stamp != nil {
+		t.stamp.fire(nil, fdb.ErrTransactionCancelled)
+		t.stamp = nil
+	}
+	t.s.mu.Lock()
+	t.release()
+	t.s.mu.Unlock()
+	t.readVersion = 0
+	t.hasReadVersion = false
+	t.committed = false
+	t.committedVersion = -1
+	t.mutations = nil
+	t.reads = nil
+	t.writes = nil
+	t.commitErr = nil
+}
+
+func (t *transaction) OnError(e fdb.Error) fdb.FutureNil {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	/* A cancelled or timed out transaction stays so until Reset */
+	if e := t.check(); e != nil {
+		return errorFutureNil(e)
+	}
+
+	if !fdb.IsRetryable(e) || (t.retryLimit >= 0 && t.retries >= t.retryLimit) {
+		return errorFutureNil(e)
+	}
+
+	t.retries++
+	t.reset()
+
+	/* Before API version 610, the timeout and retry limit did not
+	/* survive OnError */
+	if v, e := getAPIVersion(); e == nil && v < 610 {
+		t.timeout = 0
+		t.retryLimit = -1
+	}
+
+	return errorFutureNil(nil)
+}
+
+func (t *transaction) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reset()
+	t.cancelled = false
+	t.started = time.Now()
+	t.timeout = 0
+	t.retryLimit = -1
+	t.retries = 0
+}
+
+func (t *transaction) Cancel() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cancelled = true
+	t.s.mu.Lock()
+	t.release()
+	t.s.mu.Unlock()
+	t.failWatches(fdb.ErrTransactionCancelled)
+	if t.stamp != nil {
+		t.stamp.fire(nil, fdb.ErrTransactionCancelled)
+	}
+}
+
+func (t *transaction) SetOption(code int, param []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch code {
+	case 500: // timeout
+		t.timeout = time.Duration(binary.LittleEndian.Uint64(param)) * time.Millisecond
+	case 501: // retry_limit
+		t.retryLimit = int64(binary.LittleEndian.Uint64(param))
+	}
+
+	return nil
+}
@@ -21,14 +21,11 @@
 
 package fdb
 
-/*
- #define FDB_API_VERSION 200
- #include <foundationdb/fdb_c.h>
-*/
-import "C"
-
 import (
+	"bytes"
 	"fmt"
+	"iter"
+	"sort"
 )
 
 // KeyValue represents a single key-value pair in the database.
@@ -57,6 +54,38 @@ type RangeOptions struct {
 	// Limit is non-zero, the last Limit key-value pairs in the range are
 	// returned.
 	Reverse bool
+
+	// TargetBytes restricts the total size (of keys and values) of the
+	// key-value pairs returned as part of a range read. The restriction is
+	// soft: the read stops once the key-value pairs returned reach TargetBytes,
+	// so the last of them may exceed it. A value of 0 indicates no limit. If
+	// both Limit and TargetBytes are non-zero, the read stops at whichever is
+	// reached first.
+	TargetBytes int
+
+	// ReadAhead is the number of reads that an iterator over the range (a
+	// RangeIterator, or the iterators returned by All, Keys and Values) may
+	// have in progress at once. The range is divided at its split points (see
+	// GetRangeSplitPoints) into chunks of about a megabyte, and the first
+	// ReadAhead chunks not yet consumed are read at the same time, each in
+	// batches one after another, and each holding at most ReadAhead batches
+	// received but not yet consumed. The key-value pairs are still returned in
+	// order. Reading ahead may read key-value pairs (and, outside snapshot
+	// reads, add read conflict ranges for them) beyond Limit or TargetBytes.
+	//
+	// A range is divided only if both of its ends are keys (as for a KeyRange)
+	// and its split points can be read, which requires API version 700 or
+	// later; otherwise, it is read as a single chunk, with one read in
+	// progress at a time. A value of 0 reads each batch only once the previous
+	// one has been consumed.
+	ReadAhead int
+
+	// ReadAheadBytes limits the total size (of keys and values) of the batches
+	// that an iterator holds received but not yet consumed: no further batch
+	// is requested while they exceed ReadAheadBytes, except for the chunk
+	// being consumed once it has none left. A value of 0 indicates no limit.
+	// ReadAheadBytes has no effect unless ReadAhead is non-zero.
+	ReadAheadBytes int
 }
 
 // A Range describes all keys between a begin (inclusive) and end (exclusive)
@@ -124,7 +153,7 @@ type RangeResult struct {
 	sr SelectorRange
 	options RangeOptions
 	snapshot bool
-	f *futureKeyValueArray
+	f FutureKeyValueArray
 }
 
 // GetSliceWithError returns a slice of KeyValue objects satisfying the range
@@ -149,7 +178,6 @@ func (rr RangeResult) GetSliceWithError() ([]KeyValue, error) {
 		}
 		ret = append(ret, ri.kvs...)
 		ri.index = len(ri.kvs)
-		ri.fetchNextBatch()
 	}
 
 	return ret, nil
@@ -171,13 +199,69 @@ func (rr RangeResult) GetSliceOrPanic() []KeyValue {
 // Iterator returns a RangeIterator over the key-value pairs satisfying the
 // range specified in the read that returned this RangeResult.
 func (rr RangeResult) Iterator() *RangeIterator {
-	return &RangeIterator{
+	ri := &RangeIterator{
 		t: rr.t,
-		f: rr.f,
 		sr: rr.sr,
 		options: rr.options,
-		iteration: 1,
 		snapshot: rr.snapshot,
+		chunks: []*rangeChunk{{sr: rr.sr, iteration: 1, f: rr.f, shared: true, more: true}},
+	}
+
+	if rr.options.ReadAhead > 0 {
+		ri.requestSplit()
+	}
+
+	return ri
+}
+
+// All returns an iterator over the key-value pairs satisfying the range
+// specified in the read that returned this RangeResult, for use with a range
+// loop:
+//
+//	for kv, e := range tr.GetRange(r, fdb.RangeOptions{}).All() {
+//		if e != nil {
+//			return e
+//		}
+//		...
+//	}
+//
+// Batches are read lazily as the loop proceeds, in the same way as with
+// Iterator. If an asynchronous operation fails, the iterator yields the error
+// (with a zero KeyValue) and stops. If the loop exits early, any read of a
+// further batch still in progress is cancelled.
+func (rr RangeResult) All() iter.Seq2[KeyValue, error] {
+	return func(yield func(KeyValue, error) bool) {
+		ri := rr.Iterator()
+		defer ri.cancel()
+
+		for ri.Advance() {
+			kv, e := ri.Get()
+			if !yield(kv, e) || e != nil {
+				return
+			}
+		}
+	}
+}
+
+// Keys is like All, but yields only the key of each key-value pair.
+func (rr RangeResult) Keys() iter.Seq2[Key, error] {
+	return func(yield func(Key, error) bool) {
+		for kv, e := range rr.All() {
+			if !yield(kv.Key, e) {
+				return
+			}
+		}
+	}
+}
+
+// Values is like All, but yields only the value of each key-value pair.
+func (rr RangeResult) Values() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for kv, e := range rr.All() {
+			if !yield(kv.Value, e) {
+				return
+			}
+		}
 	}
 }
 
@@ -193,18 +277,66 @@ func (rr RangeResult) Iterator() *RangeIterator {
 // a transactional function passed to the Transact method of a Transactor.
 type RangeIterator struct {
 	t *transaction
-	f *futureKeyValueArray
 	sr SelectorRange
 	options RangeOptions
-	iteration int
 	done bool
-	more bool
 	kvs []KeyValue
 	index int
 	err error
 	snapshot bool
+
+	// the parts of the range not yet consumed, in the order they are read
+	chunks []*rangeChunk
+
+	// the split points of the range, while they are being read
+	split FutureKeyArray
+
+	// the key-value pairs (and their size) received by chunks already
+	// consumed, and returned by the iterator
+	rows, bytes int
+	returned, returnedBytes int
+
+	// the total size of the batches received but not yet consumed
+	queued int
+}
+
+// A rangeChunk is a part of the range read by a RangeIterator. The batches of a
+// chunk are read one after another, but a RangeIterator with a non-zero
+// ReadAhead reads several chunks at once.
+type rangeChunk struct {
+	sr SelectorRange
+	iteration int
+
+	// the keys between which the chunk lies, if it was divided from the range
+	// at its split points
+	bounded bool
+	begin, end Key
+
+	// the batch being read, if any, and whether it is the first batch of the
+	// range (which is shared with the RangeResult)
+	f FutureKeyValueArray
+	shared bool
+
+	// the batches received but not yet consumed
+	batches []rangeBatch
+
+	// the key-value pairs (and their size) received, and whether the chunk may
+	// hold more
+	rows, bytes int
+	more bool
+}
+
+// A rangeBatch is a batch of a range read received by a RangeIterator.
+type rangeBatch struct {
+	kvs []KeyValue
+	size int
+	err error
 }
 
+// readAheadChunkBytes is the approximate size of the chunks into which a range
+// read with a non-zero ReadAhead is divided. It is replaced by tests.
+var readAheadChunkBytes int64 = 1 << 20
+
 // Advance attempts to advance the iterator to the next key-value pair. Advance
 // returns true if there are more key-value pairs satisfying the range, or false
 // if the range has been exhausted. You must call this before every call to Get
@@ -214,42 +346,303 @@ func (ri *RangeIterator) Advance() bool {
 		return false
 	}
 
-	if ri.f == nil {
+	if ri.err != nil || ri.index < len(ri.kvs) {
 		return true
 	}
 
-	ri.kvs, ri.more, ri.err = ri.f.Get()
-	ri.index = 0
-	ri.f = nil
-	
-	if ri.err != nil || len(ri.kvs) > 0 {
-		return true
+	for len(ri.chunks) > 0 {
+		c := ri.chunks[0]
+
+		if len(c.batches) == 0 {
+			switch {
+			case c.f != nil:
+				ri.receive(c)
+			case c.more:
+				ri.fetch(c)
+				if ri.options.ReadAhead > 0 {
+					ri.readAhead()
+				}
+			default:
+				ri.rows += c.rows
+				ri.bytes += c.bytes
+				ri.chunks[0] = nil
+				ri.chunks = ri.chunks[1:]
+			}
+			continue
+		}
+
+		b := c.batches[0]
+		c.batches[0] = rangeBatch{}
+		c.batches = c.batches[1:]
+		ri.queued -= b.size
+
+		ri.consume(b)
+		if ri.options.ReadAhead > 0 {
+			ri.readAhead()
+		}
+
+		if ri.err != nil || len(ri.kvs) > 0 {
+			return true
+		}
 	}
 
+	ri.done = true
 	return false
 }
 
-func (ri *RangeIterator) fetchNextBatch() {
-	if !ri.more || ri.index == ri.options.Limit {
-		ri.done = true
+// consume makes b the batch being returned by the iterator, truncating it to
+// the limits (of rows or bytes) of the read. Once the iterator has returned an
+// error or reached a limit, the reads in progress are cancelled.
+func (ri *RangeIterator) consume(b rangeBatch) {
+	ri.kvs, ri.index, ri.err = b.kvs, 0, b.err
+
+	stop := ri.err != nil
+
+	if limit := ri.options.Limit; limit > 0 && ri.returned+len(ri.kvs) >= limit {
+		ri.kvs = ri.kvs[:limit-ri.returned]
+		stop = true
+	}
+	if target := ri.options.TargetBytes; target > 0 {
+		for i, kv := range ri.kvs {
+			if ri.returnedBytes += len(kv.Key) + len(kv.Value); ri.returnedBytes >= target {
+				ri.kvs = ri.kvs[:i+1]
+				stop = true
+				break
+			}
+		}
+	}
+	ri.returned += len(ri.kvs)
+
+	if stop {
+		ri.cancel()
+		ri.chunks = nil
+	}
+}
+
+// receive waits for the batch being read by c, and adds it to the batches of c
+// received but not yet consumed.
+func (ri *RangeIterator) receive(c *rangeChunk) {
+	kvs, more, e := c.f.Get()
+	c.f, c.shared = nil, false
+
+	if e != nil {
+		c.batches = append(c.batches, rangeBatch{err: e})
+		c.more = false
 		return
 	}
 
-	if ri.options.Limit > 0 {
-		// Not worried about this being zero, checked equality above
-		ri.options.Limit -= ri.index
+	/* A batch requested before the chunk was bounded may run past it */
+	if c.bounded {
+		i := sort.Search(len(kvs), func(i int) bool {
+			if ri.options.Reverse {
+				return bytes.Compare(kvs[i].Key, c.begin) < 0
+			}
+			return bytes.Compare(kvs[i].Key, c.end) >= 0
+		})
+		if i < len(kvs) {
+			kvs, more = kvs[:i], false
+		}
+	}
+
+	var size int
+	for _, kv := range kvs {
+		size += len(kv.Key) + len(kv.Value)
 	}
+	c.batches = append(c.batches, rangeBatch{kvs, size, nil})
+	c.rows += len(kvs)
+	c.bytes += size
+	ri.queued += size
+
+	c.more = more && len(kvs) > 0
+	if len(kvs) > 0 {
+		if ri.options.Reverse {
+			c.sr.End = FirstGreaterOrEqual(kvs[len(kvs)-1].Key)
+		} else {
+			c.sr.Begin = FirstGreaterThan(kvs[len(kvs)-1].Key)
+		}
+	}
+	c.iteration += 1
 
+	/* No more is needed once the chunks up to this one reach a limit */
+	rows, size := ri.received(c)
+	if ri.options.Limit > 0 && rows >= ri.options.Limit || ri.options.TargetBytes > 0 && size >= ri.options.TargetBytes {
+		c.more = false
+	}
+}
+
+// received returns the number of key-value pairs (and their size) received by
+// the chunks of the range up to and including c.
+func (ri *RangeIterator) received(c *rangeChunk) (rows, size int) {
+	rows, size = ri.rows, ri.bytes
+	for _, d := range ri.chunks {
+		rows += d.rows
+		size += d.bytes
+		if d == c {
+			break
+		}
+	}
+	return
+}
+
+// fetch requests the next batch of c, limited to what remains of the limits
+// (of rows or bytes) of the read after the batches received so far. If nothing
+// remains, c is marked as exhausted instead.
+func (ri *RangeIterator) fetch(c *rangeChunk) {
+	options := ri.options
+
+	rows, size := ri.received(c)
+	if options.Limit > 0 {
+		options.Limit -= rows
+	}
+	if options.TargetBytes > 0 {
+		options.TargetBytes -= size
+	}
+	if ri.options.Limit > 0 && options.Limit <= 0 || ri.options.TargetBytes > 0 && options.TargetBytes <= 0 {
+		c.more = false
+		return
+	}
+
+	c.f = ri.t.doGetRange(c.sr, options, ri.snapshot, c.iteration)
+}
+
+// readAhead receives the batches that have arrived, divides the range at its
+// split points once they have arrived, and requests the next batches of the
+// first ReadAhead chunks within the limits set by the ReadAhead and
+// ReadAheadBytes options.
+func (ri *RangeIterator) readAhead() {
+	if ri.split != nil && ri.split.IsReady() {
+		points, e := ri.split.Get()
+		ri.split = nil
+		if e == nil {
+			ri.divide(points)
+		}
+	}
+
+	for i, c := range ri.chunks {
+		if i == ri.options.ReadAhead {
+			break
+		}
+
+		if c.f != nil && c.f.IsReady() {
+			ri.receive(c)
+		}
+
+		if c.f != nil || !c.more || len(c.batches) >= ri.options.ReadAhead {
+			continue
+		}
+		if ri.options.ReadAheadBytes > 0 && ri.queued >= ri.options.ReadAheadBytes {
+			continue
+		}
+
+		ri.fetch(c)
+	}
+}
+
+// requestSplit requests the split points of the range, if both of its ends are
+// keys.
+func (ri *RangeIterator) requestSplit() {
+	begin, ok := selectorKey(ri.sr.Begin)
+	if !ok {
+		return
+	}
+	end, ok := selectorKey(ri.sr.End)
+	if !ok || bytes.Compare(begin, end) >= 0 {
+		return
+	}
+
+	ri.split = ri.t.getRangeSplitPoints(KeyRange{begin, end}, readAheadChunkBytes)
+}
+
+// divide divides the part of the range not yet read into chunks at the
+// provided split points. The first of the new chunks continues the read in
+// progress, if any.
+func (ri *RangeIterator) divide(points []Key) {
+	if len(ri.chunks) != 1 || !ri.chunks[0].more {
+		return
+	}
+	c := ri.chunks[0]
+
+	begin, ok := selectorKey(c.sr.Begin)
+	if !ok {
+		return
+	}
+	end, ok := selectorKey(c.sr.End)
+	if !ok {
+		return
+	}
+
+	bounds := []Key{begin}
+	for _, p := range points {
+		if bytes.Compare(p, bounds[len(bounds)-1]) > 0 && bytes.Compare(p, end) < 0 {
+			bounds = append(bounds, p)
+		}
+	}
+	if len(bounds) == 1 {
+		return
+	}
+	bounds = append(bounds, end)
+
+	chunks := make([]*rangeChunk, len(bounds)-1)
+	for i := range chunks {
+		b, e := bounds[i], bounds[i+1]
+		chunks[i] = &rangeChunk{
+			sr: SelectorRange{FirstGreaterOrEqual(b), FirstGreaterOrEqual(e)},
+			iteration: 1,
+			bounded: true,
+			begin: b,
+			end: e,
+			more: true,
+		}
+	}
 	if ri.options.Reverse {
-		ri.sr.End = FirstGreaterOrEqual(ri.kvs[ri.index-1].Key)
+		for i, j := 0, len(chunks)-1; i < j; i, j = i+1, j-1 {
+			chunks[i], chunks[j] = chunks[j], chunks[i]
+		}
+	}
+
+	first := chunks[0]
+	first.sr, first.iteration = c.sr, c.iteration
+	first.f, first.shared = c.f, c.shared
+	first.batches, first.rows, first.bytes = c.batches, c.rows, c.bytes
+	if ri.options.Reverse {
+		first.sr.Begin = FirstGreaterOrEqual(first.begin)
 	} else {
-		ri.sr.Begin = FirstGreaterThan(ri.kvs[ri.index-1].Key)
+		first.sr.End = FirstGreaterOrEqual(first.end)
 	}
 
-	ri.iteration += 1
+	ri.chunks = chunks
+}
+
+// selectorKey returns the key selected by a first-greater-or-equal or
+// first-greater-than key selector, whatever keys are present in the database.
+func selectorKey(s Selectable) (Key, bool) {
+	sel := s.FDBKeySelector()
+	if sel.Offset != 1 {
+		return nil, false
+	}
 
-	f := ri.t.doGetRange(ri.sr, ri.options, ri.snapshot, ri.iteration)
-	ri.f = &f
+	k := append(Key{}, sel.Key.FDBKey()...)
+	if sel.OrEqual {
+		k = append(k, 0x00)
+	}
+	return k, true
+}
+
+// cancel cancels the reads in progress. The first batch is shared with the
+// RangeResult (and any other iterators constructed from it), so it is never
+// cancelled.
+func (ri *RangeIterator) cancel() {
+	for _, c := range ri.chunks {
+		if c.f != nil && !c.shared {
+			c.f.Cancel()
+		}
+		c.f = nil
+	}
+
+	if ri.split != nil {
+		ri.split.Cancel()
+		ri.split = nil
+	}
 }
 
 // Get returns the next KeyValue in a range read, or an error if one of the
@@ -266,8 +659,14 @@ func (ri *RangeIterator) Get() (kv KeyValue, e error) {
 
 	ri.index += 1
 
-	if ri.index == len(ri.kvs) {
-		ri.fetchNextBatch()
+	if ri.options.ReadAhead > 0 {
+		ri.readAhead()
+	} else if ri.index == len(ri.kvs) && len(ri.chunks) > 0 {
+		/* The next batch is requested as the last key-value pair of the
+		/* batch before it is returned */
+		if c := ri.chunks[0]; len(c.batches) == 0 && c.f == nil && c.more {
+			ri.fetch(c)
+		}
 	}
 
 	return
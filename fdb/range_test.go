@@ -0,0 +1,491 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"iter"
+	"sort"
+	"testing"
+	"time"
+)
+
+// latencyBackend serves range reads of a sorted slice of key-value pairs in
+// batches (of 256 pairs, unless batch is set), each taking a fixed time to
+// become ready in the manner of reads over a network. It counts the reads in
+// progress, recording the most that were ever in progress at once, and the
+// reads cancelled. If failIteration is set, the reads of that iteration and
+// later ones fail with ErrTransactionTooOld. Split points are read with the
+// same latency, unless noSplitPoints is set, in which case they fail as they
+// do before API version 700. Other operations are not supported.
+type latencyBackend struct {
+	TransactionBackend
+	kvs []KeyValue
+	latency time.Duration
+	batch int
+	failIteration int
+	noSplitPoints bool
+
+	inflight, maxInflight, cancelled int
+}
+
+func (b *latencyBackend) CreateTransaction() (TransactionBackend, error) {
+	return b, nil
+}
+
+// resolve returns the index of the first key-value pair at or after the key
+// selected by a first-greater-or-equal or first-greater-than selector.
+func (b *latencyBackend) resolve(sel KeySelector) int {
+	return sort.Search(len(b.kvs), func(i int) bool {
+		c := bytes.Compare(b.kvs[i].Key, sel.Key.FDBKey())
+		return c > 0 || c == 0 && !sel.OrEqual
+	})
+}
+
+func (b *latencyBackend) GetRange(begin, end KeySelector, options RangeOptions, snapshot bool, iteration int) FutureKeyValueArray {
+	i, j := b.resolve(begin), b.resolve(end)
+	if j < i {
+		j = i
+	}
+
+	n := b.batch
+	if n == 0 {
+		n = 256
+	}
+	if options.Limit > 0 && options.Limit < n {
+		n = options.Limit
+	}
+	if n > j-i {
+		n = j - i
+	}
+
+	kvs := make([]KeyValue, n)
+	for k := range kvs {
+		if options.Reverse {
+			kvs[k] = b.kvs[j-1-k]
+		} else {
+			kvs[k] = b.kvs[i+k]
+		}
+	}
+	if options.TargetBytes > 0 {
+		var size int
+		for k, kv := range kvs {
+			if size += len(kv.Key) + len(kv.Value); size >= options.TargetBytes {
+				kvs = kvs[:k+1]
+				break
+			}
+		}
+	}
+
+	b.inflight++
+	if b.inflight > b.maxInflight {
+		b.maxInflight = b.inflight
+	}
+
+	f := &delayedBatch{b: b, deadline: time.Now().Add(b.latency), kvs: kvs, more: len(kvs) < j-i}
+	if b.failIteration > 0 && iteration >= b.failIteration {
+		f.kvs, f.more, f.err = nil, false, ErrTransactionTooOld
+	}
+	return f
+}
+
+func (b *latencyBackend) GetRangeSplitPoints(begin, end Key, chunkSize int64) FutureKeyArray {
+	if b.noSplitPoints {
+		return NewFutureKeyArray(nil, func() ([]Key, error) { return nil, ErrAPIVersionNotSupported }, nil)
+	}
+
+	i, j := b.resolve(FirstGreaterOrEqual(begin)), b.resolve(FirstGreaterOrEqual(end))
+	points := []Key{begin}
+	var size int64
+	for k := i; k < j; k++ {
+		if size >= chunkSize {
+			points = append(points, b.kvs[k].Key)
+			size = 0
+		}
+		size += int64(len(b.kvs[k].Key) + len(b.kvs[k].Value))
+	}
+	points = append(points, end)
+
+	ready := make(chan struct{})
+	time.AfterFunc(b.latency, func() { close(ready) })
+	return NewFutureKeyArray(ready, func() ([]Key, error) { return points, nil }, nil)
+}
+
+// delayedBatch is a FutureKeyValueArray that becomes ready at a deadline. It
+// compares the deadline with the clock rather than relying on a timer, so
+// that the benchmarks are not distorted by the scheduling of timers when the
+// reading goroutine is busy.
+type delayedBatch struct {
+	b *latencyBackend
+	deadline time.Time
+	kvs []KeyValue
+	more bool
+	err error
+}
+
+func (f *delayedBatch) BlockUntilReady() {
+	time.Sleep(time.Until(f.deadline))
+}
+
+func (f *delayedBatch) IsReady() bool {
+	return !time.Now().Before(f.deadline)
+}
+
+func (f *delayedBatch) Ready() <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		f.BlockUntilReady()
+		close(ch)
+	}()
+	return ch
+}
+
+func (f *delayedBatch) Cancel() {
+	if f.b != nil {
+		f.b.cancelled++
+	}
+	f.done()
+}
+
+func (f *delayedBatch) Get() ([]KeyValue, bool, error) {
+	f.BlockUntilReady()
+	f.done()
+	return f.kvs, f.more, f.err
+}
+
+// done marks the read as no longer in progress.
+func (f *delayedBatch) done() {
+	if f.b != nil {
+		f.b.inflight--
+		f.b = nil
+	}
+}
+
+// benchmarkRange scans 4096 key-value pairs in batches of 256 (each read
+// taking a millisecond), hashing each value to simulate processing. The range
+// is divided into chunks of 256 key-value pairs, so that each chunk is read in
+// a single batch. It reports the most reads that were in progress at once.
+func benchmarkRange(b *testing.B, options RangeOptions) {
+	setReadAheadChunkBytes(b, 256 * (6 + 4096) - 1)
+
+	backend := &latencyBackend{latency: time.Millisecond}
+	for i := 0; i < 4096; i++ {
+		backend.kvs = append(backend.kvs, KeyValue{Key(fmt.Sprintf("k%05d", i)), make([]byte, 4096)})
+	}
+	db := NewDatabase(backend)
+
+	for i := 0; i < b.N; i++ {
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			b.Fatal(e)
+		}
+
+		var n int
+		for kv, e := range tr.GetRange(KeyRange{Key("k"), Key("l")}, options).All() {
+			if e != nil {
+				b.Fatal(e)
+			}
+			sha256.Sum256(kv.Value)
+			n++
+		}
+		if n != len(backend.kvs) {
+			b.Fatalf("read %d key-value pairs, expected %d", n, len(backend.kvs))
+		}
+	}
+
+	b.ReportMetric(float64(backend.maxInflight), "max-reads")
+}
+
+// setReadAheadChunkBytes sets the size of the chunks into which ranges are
+// divided for reading ahead, for the duration of a test or benchmark.
+func setReadAheadChunkBytes(tb testing.TB, n int64) {
+	old := readAheadChunkBytes
+	readAheadChunkBytes = n
+	tb.Cleanup(func() { readAheadChunkBytes = old })
+}
+
+func BenchmarkRangeSerial(b *testing.B) {
+	benchmarkRange(b, RangeOptions{})
+}
+
+func BenchmarkRangeReadAhead(b *testing.B) {
+	benchmarkRange(b, RangeOptions{ReadAhead: 4})
+}
+
+func BenchmarkRangeReadAhead16(b *testing.B) {
+	benchmarkRange(b, RangeOptions{ReadAhead: 16})
+}
+
+func BenchmarkRangeReadAheadBytes(b *testing.B) {
+	benchmarkRange(b, RangeOptions{ReadAhead: 4, ReadAheadBytes: 2 << 20})
+}
+
+func TestRangeReadAhead(t *testing.T) {
+	/* Chunks of about 100 key-value pairs, read in batches of 64 */
+	setReadAheadChunkBytes(t, 3000)
+
+	backend := &latencyBackend{batch: 64, latency: time.Millisecond}
+	var maxBatch int
+	for i := 0; i < 1000; i++ {
+		kv := KeyValue{Key(fmt.Sprintf("k%05d", i)), make([]byte, i%50)}
+		backend.kvs = append(backend.kvs, kv)
+		maxBatch = max(maxBatch, 64 * (len(kv.Key) + len(kv.Value)))
+	}
+	db := NewDatabase(backend)
+
+	tests := []struct {
+		name string
+		options RangeOptions
+		noSplitPoints bool
+		concurrent bool
+	}{
+		{"serial", RangeOptions{}, false, false},
+		{"read ahead", RangeOptions{ReadAhead: 4}, false, true},
+		{"read ahead of 1", RangeOptions{ReadAhead: 1}, false, false},
+		{"read ahead past the end", RangeOptions{ReadAhead: 100}, false, true},
+		{"without split points", RangeOptions{ReadAhead: 4}, true, false},
+		{"without split points reverse", RangeOptions{ReadAhead: 4, Reverse: true}, true, false},
+		{"limit", RangeOptions{ReadAhead: 4, Limit: 300}, false, true},
+		{"limit of whole batches", RangeOptions{ReadAhead: 4, Limit: 128}, false, true},
+		{"limit within a batch", RangeOptions{ReadAhead: 4, Limit: 10}, false, false},
+		{"reverse", RangeOptions{ReadAhead: 4, Reverse: true}, false, true},
+		{"reverse limit", RangeOptions{ReadAhead: 3, Reverse: true, Limit: 330}, false, true},
+		{"read ahead bytes", RangeOptions{ReadAhead: 8, ReadAheadBytes: 3000}, false, false},
+		{"read ahead bytes reverse", RangeOptions{ReadAhead: 8, ReadAheadBytes: 1, Reverse: true}, false, false},
+		{"target bytes", RangeOptions{ReadAhead: 4, TargetBytes: 10000}, false, true},
+		{"target bytes limit reverse", RangeOptions{ReadAhead: 4, TargetBytes: 10000, Limit: 200, Reverse: true}, false, true},
+		{"exact mode", RangeOptions{ReadAhead: 4, Limit: 500, Mode: StreamingModeExact}, false, true},
+	}
+
+	for _, tt := range tests {
+		var expected []KeyValue
+		var size int
+		for i := range backend.kvs {
+			kv := backend.kvs[i]
+			if tt.options.Reverse {
+				kv = backend.kvs[len(backend.kvs)-1-i]
+			}
+			if tt.options.Limit > 0 && len(expected) == tt.options.Limit || tt.options.TargetBytes > 0 && size >= tt.options.TargetBytes {
+				break
+			}
+			expected = append(expected, kv)
+			size += len(kv.Key) + len(kv.Value)
+		}
+
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+		backend.maxInflight, backend.cancelled, backend.noSplitPoints = 0, 0, tt.noSplitPoints
+
+		var read []KeyValue
+		ri := tr.GetRange(KeyRange{Key("k"), Key("l")}, tt.options).Iterator()
+		for ri.Advance() {
+			kv, e := ri.Get()
+			if e != nil {
+				t.Fatalf("%s: %v", tt.name, e)
+			}
+			read = append(read, kv)
+
+			for _, c := range ri.chunks {
+				if n := len(c.batches); n > max(tt.options.ReadAhead, 1) {
+					t.Errorf("%s: %d batches of a chunk read ahead, expected at most %d", tt.name, n, tt.options.ReadAhead)
+				}
+			}
+			if limit := tt.options.ReadAheadBytes; limit > 0 && ri.queued >= limit + tt.options.ReadAhead * maxBatch {
+				t.Errorf("%s: read ahead %d bytes, expected a batch to be requested only below %d", tt.name, ri.queued, limit)
+			}
+		}
+
+		if len(read) != len(expected) {
+			t.Errorf("%s: read %d key-value pairs, expected %d", tt.name, len(read), len(expected))
+			continue
+		}
+		for i := range read {
+			if !bytes.Equal(read[i].Key, expected[i].Key) {
+				t.Errorf("%s: read %s at position %d, expected %s", tt.name, read[i].Key, i, expected[i].Key)
+				break
+			}
+		}
+
+		switch {
+		case backend.maxInflight > max(tt.options.ReadAhead, 1):
+			t.Errorf("%s: %d reads in progress at once, expected at most %d", tt.name, backend.maxInflight, max(tt.options.ReadAhead, 1))
+		case tt.concurrent && backend.maxInflight < 2:
+			t.Errorf("%s: %d reads in progress at once, expected reads to be concurrent", tt.name, backend.maxInflight)
+		case !tt.concurrent && tt.options.ReadAheadBytes == 0 && backend.maxInflight > 1:
+			t.Errorf("%s: %d reads in progress at once, expected 1", tt.name, backend.maxInflight)
+		}
+		if backend.inflight != 0 {
+			t.Errorf("%s: %d reads still in progress", tt.name, backend.inflight)
+		}
+	}
+}
+
+// newRangeBackend returns a latencyBackend (serving reads immediately, in
+// batches of 64) holding n key-value pairs, and a transaction reading it.
+func newRangeBackend(t *testing.T, n int) (*latencyBackend, Transaction) {
+	backend := &latencyBackend{batch: 64}
+	for i := 0; i < n; i++ {
+		backend.kvs = append(backend.kvs, KeyValue{Key(fmt.Sprintf("k%05d", i)), []byte(fmt.Sprintf("v%d", i))})
+	}
+
+	tr, e := NewDatabase(backend).CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+	return backend, tr
+}
+
+func TestRangeAll(t *testing.T) {
+	backend, tr := newRangeBackend(t, 200)
+	r := KeyRange{Key("k"), Key("l")}
+
+	var i int
+	for kv, e := range tr.GetRange(r, RangeOptions{}).All() {
+		if e != nil {
+			t.Fatal(e)
+		}
+		if !bytes.Equal(kv.Key, backend.kvs[i].Key) || !bytes.Equal(kv.Value, backend.kvs[i].Value) {
+			t.Fatalf("All yielded %s=%s at position %d, expected %s=%s", kv.Key, kv.Value, i, backend.kvs[i].Key, backend.kvs[i].Value)
+		}
+		i++
+	}
+	if i != len(backend.kvs) {
+		t.Errorf("All yielded %d key-value pairs, expected %d", i, len(backend.kvs))
+	}
+
+	i = 0
+	for k, e := range tr.GetRange(r, RangeOptions{Reverse: true}).Keys() {
+		if e != nil {
+			t.Fatal(e)
+		}
+		if expected := backend.kvs[len(backend.kvs)-1-i].Key; !bytes.Equal(k, expected) {
+			t.Fatalf("Keys yielded %s at position %d, expected %s", k, i, expected)
+		}
+		i++
+	}
+	if i != len(backend.kvs) {
+		t.Errorf("Keys yielded %d keys, expected %d", i, len(backend.kvs))
+	}
+
+	i = 0
+	for v, e := range tr.GetRange(r, RangeOptions{Limit: 100}).Values() {
+		if e != nil {
+			t.Fatal(e)
+		}
+		if expected := backend.kvs[i].Value; !bytes.Equal(v, expected) {
+			t.Fatalf("Values yielded %s at position %d, expected %s", v, i, expected)
+		}
+		i++
+	}
+	if i != 100 {
+		t.Errorf("Values yielded %d values, expected 100", i)
+	}
+
+	if backend.inflight != 0 {
+		t.Errorf("%d reads still in progress", backend.inflight)
+	}
+}
+
+func TestRangeAllBreak(t *testing.T) {
+	backend, tr := newRangeBackend(t, 200)
+
+	/* The last key-value pair of the first batch requests the second */
+	var n int
+	for _, e := range tr.GetRange(KeyRange{Key("k"), Key("l")}, RangeOptions{}).All() {
+		if e != nil {
+			t.Fatal(e)
+		}
+		if n++; n == backend.batch {
+			if backend.inflight != 1 {
+				t.Fatalf("%d reads in progress before break, expected 1", backend.inflight)
+			}
+			break
+		}
+	}
+
+	if backend.cancelled != 1 || backend.inflight != 0 {
+		t.Errorf("%d reads cancelled, %d still in progress; expected the read of the second batch to be cancelled", backend.cancelled, backend.inflight)
+	}
+}
+
+func TestRangeAllError(t *testing.T) {
+	tests := []struct {
+		name string
+		errs func(RangeResult) iter.Seq[error]
+	}{
+		{"All", func(rr RangeResult) iter.Seq[error] {
+			return func(yield func(error) bool) {
+				for _, e := range rr.All() {
+					if !yield(e) {
+						return
+					}
+				}
+			}
+		}},
+		{"Keys", func(rr RangeResult) iter.Seq[error] {
+			return func(yield func(error) bool) {
+				for _, e := range rr.Keys() {
+					if !yield(e) {
+						return
+					}
+				}
+			}
+		}},
+		{"Values", func(rr RangeResult) iter.Seq[error] {
+			return func(yield func(error) bool) {
+				for _, e := range rr.Values() {
+					if !yield(e) {
+						return
+					}
+				}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		backend, tr := newRangeBackend(t, 200)
+		backend.failIteration = 2
+
+		var n, errs int
+		for e := range tt.errs(tr.GetRange(KeyRange{Key("k"), Key("l")}, RangeOptions{})) {
+			if e != nil {
+				if e != ErrTransactionTooOld {
+					t.Errorf("%s: yielded %v, expected ErrTransactionTooOld", tt.name, e)
+				}
+				errs++
+				continue
+			}
+			if errs > 0 {
+				t.Errorf("%s: yielded a key-value pair after an error", tt.name)
+			}
+			n++
+		}
+
+		if n != backend.batch || errs != 1 {
+			t.Errorf("%s: yielded %d key-value pairs and %d errors, expected %d and 1", tt.name, n, errs, backend.batch)
+		}
+	}
+}
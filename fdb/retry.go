@@ -0,0 +1,165 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy controls the retry loop used by the Transact and ReadTransact
+// methods of a Database. A RetryPolicy is attached to a Database handle with
+// the (Database).WithRetryPolicy method.
+//
+// The zero value of RetryPolicy represents the default behavior: errors are
+// retried for as long as (Transaction).OnError considers them retryable, and
+// any error that is not an Error is returned to the caller immediately.
+type RetryPolicy struct {
+	// MaxAttempts limits the number of times the transactional function is
+	// called. A value of 0 indicates no limit. The limit is enforced with
+	// (TransactionOptions).SetRetryLimit.
+	MaxAttempts int
+
+	// MaxElapsed limits the total time spent executing and retrying the
+	// transactional function. A value of 0 indicates no limit. The limit is
+	// enforced with (TransactionOptions).SetTimeout, so outstanding reads and
+	// commits fail with a transaction_timed_out error once it elapses.
+	MaxElapsed time.Duration
+
+	// OnRetry, if non-nil, is called before each retry with the number of
+	// attempts made so far and the error that caused the most recent attempt to
+	// fail.
+	OnRetry func(attempt int, e error)
+
+	// Retryable, if non-nil, classifies errors returned by the transactional
+	// function that are not of type Error. If Retryable returns true, the
+	// transaction is reset and the function retried (subject to MaxAttempts and
+	// MaxElapsed); otherwise the error is returned to the caller.
+	Retryable func(e error) bool
+}
+
+// apply sets the transaction options enforcing the limits of the policy,
+// accounting for the attempts already made and time already spent.
+func (p RetryPolicy) apply(tr Transaction, attempts int, elapsed time.Duration) error {
+	if p.MaxAttempts > 0 {
+		if e := tr.Options().SetRetryLimit(int64(p.MaxAttempts - attempts - 1)); e != nil {
+			return e
+		}
+	}
+
+	if p.MaxElapsed > 0 {
+		/* A timeout of 0 would disable the timeout entirely */
+		ms := int64((p.MaxElapsed - elapsed) / time.Millisecond)
+		if ms < 1 {
+			ms = 1
+		}
+		if e := tr.Options().SetTimeout(ms); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// limitsClearedByOnError reports whether (Transaction).OnError clears the retry
+// limit and timeout of a transaction, as it does before API version 610.
+func limitsClearedByOnError() bool {
+	v, e := GetAPIVersion()
+	return e == nil && v < 610
+}
+
+func (p RetryPolicy) exhausted(attempts int, elapsed time.Duration) bool {
+	return (p.MaxAttempts > 0 && attempts >= p.MaxAttempts) || (p.MaxElapsed > 0 && elapsed >= p.MaxElapsed)
+}
+
+func retryable(ctx context.Context, policy RetryPolicy, tr Transaction, wrapped func() (interface{}, error)) (ret interface{}, e error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	start := time.Now()
+
+	/* The attempts made and time spent when the limits of the policy were
+	/* last applied, which the limits set on the transaction account for */
+	var applied int
+	var appliedElapsed time.Duration
+
+	if e = policy.apply(tr, 0, 0); e != nil {
+		return nil, e
+	}
+
+	for attempt := 1; ; attempt++ {
+		ret, e = wrapped()
+
+		/* No error means success! */
+		if e == nil {
+			return
+		}
+
+		/* A finished context stops the retry loop, whatever the error */
+		if ctx.Err() != nil {
+			return nil, contextError(ctx, e)
+		}
+
+		last := e
+
+		ep, ok := e.(Error)
+		if ok {
+			e = tr.OnError(ep).Get()
+			if e == nil && limitsClearedByOnError() {
+				/* OnError discards the limits set on the transaction
+				/* but keeps counting retries and time from the last
+				/* Reset, so the same limits are applied again */
+				e = policy.apply(tr, applied, appliedElapsed)
+			}
+		} else if policy.Retryable != nil && policy.Retryable(e) && !policy.exhausted(attempt, time.Since(start)) {
+			/* Reset discards the limits set on the transaction, so they
+			/* are applied again with whatever remains of them */
+			tr.Reset()
+			applied, appliedElapsed = attempt, time.Since(start)
+			e = policy.apply(tr, applied, appliedElapsed)
+		}
+
+		/* If OnError returns an error, then it's not
+		/* retryable; otherwise take another pass at things */
+		if e != nil {
+			if ctx.Err() != nil {
+				e = contextError(ctx, e)
+			}
+			return
+		}
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, last)
+		}
+	}
+}
+
+// contextError returns the error of a finished context, wrapped together with
+// the last error encountered by the transactional function (if any).
+func contextError(ctx context.Context, e error) error {
+	if e == nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w (last error: %w)", ctx.Err(), e)
+}
@@ -0,0 +1,239 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package fdb_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+var errTemporary = errors.New("temporary failure")
+
+// failing returns a transactional function that fails with e in the first n
+// calls (or every call, if n is negative), counting the calls made.
+func failing(calls *int, n int, e error) func(fdb.Transaction) (interface{}, error) {
+	return func(tr fdb.Transaction) (interface{}, error) {
+		*calls++
+		if n < 0 || *calls <= n {
+			return nil, e
+		}
+		tr.Set(fdb.Key("key"), []byte("value"))
+		return *calls, nil
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	retryable := func(e error) bool { return errors.Is(e, errTemporary) }
+
+	tests := []struct {
+		name string
+		policy fdb.RetryPolicy
+		n int
+		e error
+		calls int
+		err error
+	}{
+		{"default", fdb.RetryPolicy{}, 3, fdb.ErrNotCommitted, 4, nil},
+		{"MaxAttempts", fdb.RetryPolicy{MaxAttempts: 3}, -1, fdb.ErrNotCommitted, 3, fdb.ErrNotCommitted},
+		{"MaxAttempts not reached", fdb.RetryPolicy{MaxAttempts: 3}, 2, fdb.ErrTransactionTooOld, 3, nil},
+		{"MaxAttempts of 1", fdb.RetryPolicy{MaxAttempts: 1}, -1, fdb.ErrNotCommitted, 1, fdb.ErrNotCommitted},
+		{"not Retryable", fdb.RetryPolicy{}, 2, errTemporary, 1, errTemporary},
+		{"Retryable", fdb.RetryPolicy{Retryable: retryable}, 2, errTemporary, 3, nil},
+		{"Retryable with MaxAttempts", fdb.RetryPolicy{MaxAttempts: 2, Retryable: retryable}, -1, errTemporary, 2, errTemporary},
+		{"non-retryable Error", fdb.RetryPolicy{Retryable: retryable}, -1, fdb.ErrInvertedRange, 1, fdb.ErrInvertedRange},
+	}
+
+	for _, tt := range tests {
+		db := memdb.New().WithRetryPolicy(tt.policy)
+
+		var calls int
+		_, e := db.Transact(failing(&calls, tt.n, tt.e))
+		if calls != tt.calls {
+			t.Errorf("%s: function called %d times, expected %d", tt.name, calls, tt.calls)
+		}
+		if tt.err == nil && e != nil {
+			t.Errorf("%s: %v", tt.name, e)
+		}
+		if tt.err != nil && !errors.Is(e, tt.err) {
+			t.Errorf("%s: returned %v, expected %v", tt.name, e, tt.err)
+		}
+	}
+}
+
+func TestRetryPolicyInjectedFaults(t *testing.T) {
+	/* Every commit fails with a retryable error */
+	db := memdb.New().WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 1})
+
+	var calls int
+	_, e := db.WithRetryPolicy(fdb.RetryPolicy{MaxAttempts: 5}).Transact(failing(&calls, 0, nil))
+	if calls != 5 {
+		t.Errorf("function called %d times, expected 5", calls)
+	}
+	if !fdb.IsRetryable(e) {
+		t.Errorf("returned %v, expected an injected retryable error", e)
+	}
+}
+
+func TestRetryPolicyMaxElapsed(t *testing.T) {
+	db := memdb.New().WithRetryPolicy(fdb.RetryPolicy{MaxElapsed: 50 * time.Millisecond})
+
+	start := time.Now()
+	var calls int
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		if _, e := tr.Get(fdb.Key("key")).Get(); e != nil {
+			return nil, e
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil, fdb.ErrNotCommitted
+	})
+
+	if !errors.Is(e, fdb.ErrTransactionTimedOut) {
+		t.Errorf("returned %v, expected ErrTransactionTimedOut", e)
+	}
+	if calls < 2 {
+		t.Errorf("function called %d times, expected it to be retried", calls)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("retried for %v, beyond MaxElapsed", d)
+	}
+
+	/* The time remaining is applied again after a Retryable error */
+	db = memdb.New().WithRetryPolicy(fdb.RetryPolicy{
+		MaxElapsed: 50 * time.Millisecond,
+		Retryable: func(e error) bool { return errors.Is(e, errTemporary) },
+	})
+
+	calls = 0
+	_, e = db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		time.Sleep(5 * time.Millisecond)
+		return nil, errTemporary
+	})
+
+	if !errors.Is(e, errTemporary) {
+		t.Errorf("returned %v, expected errTemporary", e)
+	}
+	if calls < 2 || calls > 11 {
+		t.Errorf("function called %d times, expected about 10", calls)
+	}
+}
+
+func TestRetryPolicyOnRetry(t *testing.T) {
+	type retry struct {
+		attempt int
+		e error
+	}
+	var retries []retry
+
+	db := memdb.New().WithRetryPolicy(fdb.RetryPolicy{
+		OnRetry: func(attempt int, e error) { retries = append(retries, retry{attempt, e}) },
+		Retryable: func(e error) bool { return errors.Is(e, errTemporary) },
+	})
+
+	errs := []error{fdb.ErrNotCommitted, errTemporary, fdb.ErrTransactionTooOld}
+	var calls int
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		if calls <= len(errs) {
+			return nil, errs[calls-1]
+		}
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if len(retries) != len(errs) {
+		t.Fatalf("OnRetry called %d times, expected %d", len(retries), len(errs))
+	}
+	for i, r := range retries {
+		if r.attempt != i+1 || r.e != errs[i] {
+			t.Errorf("OnRetry called with (%d, %v), expected (%d, %v)", r.attempt, r.e, i+1, errs[i])
+		}
+	}
+}
+
+func TestRetryPolicyBeforeAPIVersion610(t *testing.T) {
+	/* OnError clears the retry limit and timeout before API version 610 */
+	fdb.SelectAPIVersion(t, 200)
+
+	db := memdb.New().WithRetryPolicy(fdb.RetryPolicy{MaxAttempts: 3})
+
+	var calls int
+	_, e := db.Transact(failing(&calls, -1, fdb.ErrNotCommitted))
+	if calls != 3 {
+		t.Errorf("function called %d times, expected 3", calls)
+	}
+	if !errors.Is(e, fdb.ErrNotCommitted) {
+		t.Errorf("returned %v, expected ErrNotCommitted", e)
+	}
+
+	db = memdb.New().WithRetryPolicy(fdb.RetryPolicy{MaxElapsed: 50 * time.Millisecond})
+
+	start := time.Now()
+	calls = 0
+	_, e = db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		if _, e := tr.Get(fdb.Key("key")).Get(); e != nil {
+			return nil, e
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil, fdb.ErrNotCommitted
+	})
+
+	if !errors.Is(e, fdb.ErrTransactionTimedOut) {
+		t.Errorf("returned %v, expected ErrTransactionTimedOut", e)
+	}
+	if calls < 2 {
+		t.Errorf("function called %d times, expected it to be retried", calls)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("retried for %v, beyond MaxElapsed", d)
+	}
+
+	/* Limits applied again after a Retryable error are kept too */
+	db = memdb.New().WithRetryPolicy(fdb.RetryPolicy{
+		MaxAttempts: 4,
+		Retryable: func(e error) bool { return errors.Is(e, errTemporary) },
+	})
+
+	calls = 0
+	_, e = db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, errTemporary
+		}
+		return nil, fdb.ErrNotCommitted
+	})
+	if calls != 4 {
+		t.Errorf("function called %d times, expected 4", calls)
+	}
+	if !errors.Is(e, fdb.ErrNotCommitted) {
+		t.Errorf("returned %v, expected ErrNotCommitted", e)
+	}
+}
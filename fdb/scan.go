@@ -0,0 +1,486 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+)
+
+// scanBuffer is the number of batches that each split of an ordered parallel
+// scan may read ahead of the caller.
+const scanBuffer = 8
+
+// A scanBatch is a batch of key-value pairs read by a parallel scan, or the
+// error that stopped the scan of a split.
+type scanBatch struct {
+	kvs []KeyValue
+	err error
+}
+
+// ParallelScan reads every key-value pair in the provided range, calling fn
+// with each. The range is split at the boundaries between shards (as returned
+// by LocalityGetBoundaryKeys), and up to workers splits are read at once, each
+// in its own transaction. ParallelScan calls fn from the calling goroutine
+// (never concurrently), in no particular order; use ParallelScanOrdered to
+// receive the key-value pairs in key order.
+//
+// Unlike a range read within a single transaction, a parallel scan may read
+// ranges too large to be read within the five seconds allowed to a
+// transaction: when a read fails with a retryable error (such as
+// ErrTransactionTooOld), the scan of the split continues after the last key
+// read, in a new transaction. These retries are limited by the RetryPolicy of
+// d, if any, as for its Transact method. The key-value pairs of different
+// splits (and of a single split, once restarted) may therefore be read at
+// different versions, and do not form a consistent snapshot of the range.
+//
+// If fn returns an error, or a read fails with an error that is not retryable,
+// the scan stops and ParallelScan returns the error.
+func ParallelScan(d Database, er ExactRange, workers int, fn func(KeyValue) error) error {
+	return parallelScan(d, er, workers, false, fn)
+}
+
+// ParallelScanOrdered is like ParallelScan, but calls fn with the key-value
+// pairs in key order. Splits are still read in parallel, but each may read only
+// a few batches ahead of the split being passed to fn, so a slow fn limits the
+// parallelism of the scan.
+func ParallelScanOrdered(d Database, er ExactRange, workers int, fn func(KeyValue) error) error {
+	return parallelScan(d, er, workers, true, fn)
+}
+
+func parallelScan(d Database, er ExactRange, workers int, ordered bool, fn func(KeyValue) error) error {
+	splits, e := scanSplits(d, er)
+	if e != nil {
+		return e
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// Splits are dispatched to workers in order, so that every split before
+	// one being read has been (or is being) read.
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := range splits {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	chans := make([]chan scanBatch, len(splits))
+	shared := make(chan scanBatch, workers)
+	for i := range chans {
+		if ordered {
+			chans[i] = make(chan scanBatch, scanBuffer)
+		} else {
+			chans[i] = shared
+		}
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				scanSplit(ctx, d, splits[i], chans[i])
+				if ordered {
+					close(chans[i])
+				}
+			}
+		}()
+	}
+
+	handle := func(b scanBatch) error {
+		if b.err != nil {
+			return b.err
+		}
+		for _, kv := range b.kvs {
+			if e := fn(kv); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	if !ordered {
+		go func() {
+			wg.Wait()
+			close(shared)
+		}()
+
+		for b := range shared {
+			if e := handle(b); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	for _, ch := range chans {
+		for b := range ch {
+			if e := handle(b); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// scanSplits returns the ranges into which a parallel scan of the provided
+// range is split, in key order.
+func scanSplits(d Database, er ExactRange) ([]KeyRange, error) {
+	bk, ek := er.FDBRangeKeys()
+	begin, end := bk.FDBKey(), ek.FDBKey()
+
+	tr, e := d.CreateTransaction()
+	if e != nil {
+		return nil, e
+	}
+	defer tr.Cancel()
+
+	ret, e := retryable(context.Background(), d.policy(), tr, func() (interface{}, error) {
+		return localityGetBoundaryKeys(tr, er, 0)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	var splits []KeyRange
+	for _, k := range ret.([]Key) {
+		if bytes.Compare(k, begin) > 0 && bytes.Compare(k, end) < 0 {
+			splits = append(splits, KeyRange{begin, k})
+			begin = k
+		}
+	}
+
+	return append(splits, KeyRange{begin, end}), nil
+}
+
+// scanSplit reads the key-value pairs of a split, sending them in batches on
+// ch, until the split is exhausted, a read fails with an error that is not
+// retryable or exhausts the retry policy of d (the error is sent on ch), or ctx
+// is cancelled. After a retryable error, the read continues after the last key
+// sent. The retry policy bounds the attempts that make no progress: once an
+// attempt has sent some of the split before failing, its limits are applied
+// afresh, so that a long split read over many transactions is not failed
+// while every transaction advances it.
+func scanSplit(ctx context.Context, d Database, kr KeyRange, ch chan<- scanBatch) {
+	send := func(b scanBatch) bool {
+		select {
+		case ch <- b:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	tr, e := d.CreateTransaction()
+	if e != nil {
+		send(scanBatch{err: e})
+		return
+	}
+	defer tr.Cancel()
+
+	sr := SelectorRange{FirstGreaterOrEqual(kr.Begin), FirstGreaterOrEqual(kr.End)}
+
+	p := d.policy()
+	retry := p.Retryable
+	p.Retryable = func(e error) bool {
+		/* An attempt that made progress is retried below */
+		if _, ok := e.(scanProgress); ok {
+			return false
+		}
+		return retry != nil && retry(e)
+	}
+
+	for {
+		var attempts int
+		_, e = retryable(ctx, p, tr, func() (interface{}, error) {
+			attempts++
+			progressed := false
+
+			ri := tr.Snapshot().GetRange(sr, RangeOptions{Mode: StreamingModeWantAll}).Iterator()
+			for ri.Advance() && ri.err == nil {
+				if !send(scanBatch{kvs: ri.kvs}) {
+					return nil, ctx.Err()
+				}
+				sr.Begin = FirstGreaterThan(ri.kvs[len(ri.kvs)-1].Key)
+				ri.index = len(ri.kvs)
+				progressed = true
+			}
+
+			if ep, ok := ri.err.(Error); ok && progressed {
+				return nil, scanProgress{ep}
+			}
+			return nil, ri.err
+		})
+
+		sp, ok := e.(scanProgress)
+		if !ok {
+			break
+		}
+
+		/* OnError decides whether (and after what delay) to retry,
+		/* free of the limits of the policy, which are cleared by a
+		/* reset; the retry it counts is cleared by another, so that
+		/* retryable applies the limits afresh */
+		tr.Reset()
+		if e = tr.OnError(sp.err).Get(); e != nil {
+			break
+		}
+		tr.Reset()
+		if p.OnRetry != nil {
+			p.OnRetry(attempts, sp.err)
+		}
+	}
+	if e != nil && ctx.Err() == nil {
+		send(scanBatch{err: e})
+	}
+}
+
+// scanProgress is the error of an attempt of scanSplit that read some of the
+// split before failing with err.
+type scanProgress struct {
+	err Error
+}
+
+func (e scanProgress) Error() string {
+	return e.err.Error()
+}
+
+// ErrInvalidContinuation is the error reported by a Scanner resumed from a
+// continuation token that was not returned by (*Scanner).Continuation, or that
+// was returned by a scan in the other direction.
+var ErrInvalidContinuation = errors.New("invalid scan continuation token")
+
+// defaultTransactionLimit is the default number of key-value pairs read by each
+// transaction of a Scanner.
+const defaultTransactionLimit = 10000
+
+// Continuation tokens begin with a version byte and a byte of flags, followed
+// by the last key returned by the scan.
+const (
+	continuationVersion = 0x01
+
+	continuationReverse = 1 << 0
+	continuationDone = 1 << 1
+)
+
+// ScanOptions specify how a range is read by ScanAll.
+type ScanOptions struct {
+	// Reverse indicates that the range should be read in reverse
+	// lexicographic order.
+	Reverse bool
+
+	// TransactionLimit is the number of key-value pairs read by each
+	// transaction of the scan. A value of 0 indicates the default of 10000.
+	TransactionLimit int
+
+	// Continuation resumes a scan from a continuation token returned by
+	// (*Scanner).Continuation, which must have been returned by a scan of the
+	// same range in the same direction. A nil Continuation begins the scan at
+	// the start of the range.
+	Continuation []byte
+}
+
+// Scanner reads a range of keys too large to be read within a single
+// transaction. A Scanner is constructed with ScanAll, and should not be used
+// concurrently from multiple goroutines.
+//
+// You must call Next and get a true result prior to calling KeyValue. A
+// Scanner should not be used within a transactional function passed to the
+// Transact method of a Transactor.
+type Scanner struct {
+	t Transactor
+	sr SelectorRange
+	options ScanOptions
+	kvs []KeyValue
+	index int
+	exhausted bool
+	positioned bool
+	last Key
+	err error
+}
+
+// ScanAll returns a Scanner reading every key-value pair in the provided range.
+// The range is read in a series of transactions, each reading up to
+// options.TransactionLimit key-value pairs with snapshot reads and continuing
+// after the last key read by the previous one. A transaction that grows too old
+// (failing with ErrTransactionTooOld) ends early, keeping the key-value pairs it
+// has read, or is retried with a smaller limit if it has read none; a
+// transaction that fails with any other retryable error is retried by the
+// Transact method of t. Each transaction reads at a new read version, so the
+// key-value pairs returned do not form a consistent snapshot of the range.
+//
+// At any point, the position of the scan may be saved with Continuation, and
+// the scan resumed later (for example, after a process restart) by passing the
+// token to ScanAll as options.Continuation.
+//
+// If t is a Transaction rather than a Database, every read is performed in that
+// transaction, and the range must be small enough to be read within it.
+func ScanAll(t Transactor, r Range, options ScanOptions) *Scanner {
+	begin, end := r.FDBRangeKeySelectors()
+	s := &Scanner{
+		t: t,
+		sr: SelectorRange{begin, end},
+		options: options,
+	}
+
+	if s.options.TransactionLimit <= 0 {
+		s.options.TransactionLimit = defaultTransactionLimit
+	}
+
+	c := options.Continuation
+	switch {
+	case c == nil:
+	case len(c) < 2 || c[0] != continuationVersion || (c[1] & continuationReverse != 0) != options.Reverse:
+		s.err = ErrInvalidContinuation
+	case c[1] & continuationDone != 0:
+		s.exhausted = true
+	default:
+		s.positioned, s.last = true, Key(c[2:])
+		s.resumeAfter(s.last)
+	}
+
+	return s
+}
+
+// resumeAfter moves the range still to be read to follow key.
+func (s *Scanner) resumeAfter(key Key) {
+	if s.options.Reverse {
+		s.sr.End = FirstGreaterOrEqual(key)
+	} else {
+		s.sr.Begin = FirstGreaterThan(key)
+	}
+}
+
+// Next advances the Scanner to the next key-value pair, reading it in a new
+// transaction if necessary. Next returns false when the range has been
+// exhausted, or if a read failed (in which case Err returns the error).
+func (s *Scanner) Next() bool {
+	if s.err != nil {
+		return false
+	}
+
+	for s.index == len(s.kvs) {
+		if s.exhausted {
+			return false
+		}
+		s.fetch()
+		if s.err != nil {
+			return false
+		}
+	}
+
+	s.positioned, s.last = true, s.kvs[s.index].Key
+	s.index += 1
+	return true
+}
+
+// fetch reads the next transaction's worth of key-value pairs. If a read fails
+// with ErrTransactionTooOld, the key-value pairs already read by the
+// transaction are kept and the next transaction continues after them; if none
+// were read, the transaction is retried with half the limit.
+func (s *Scanner) fetch() {
+	limit := s.options.TransactionLimit
+	var kvs []KeyValue
+	var stopped bool
+
+	_, e := s.t.Transact(func(tr Transaction) (interface{}, error) {
+		kvs, stopped = kvs[:0], false
+
+		ri := tr.Snapshot().GetRange(s.sr, RangeOptions{Limit: limit, Reverse: s.options.Reverse}).Iterator()
+		for ri.Advance() {
+			kv, e := ri.Get()
+			if e != nil {
+				if !errors.Is(e, ErrTransactionTooOld) {
+					return nil, e
+				}
+				if len(kvs) > 0 {
+					stopped = true
+					return nil, nil
+				}
+				if limit > 1 {
+					limit /= 2
+				}
+				return nil, e
+			}
+			kvs = append(kvs, kv)
+		}
+		return nil, nil
+	})
+	if e != nil {
+		s.err = e
+		return
+	}
+
+	s.kvs, s.index = kvs, 0
+	s.exhausted = !stopped && len(s.kvs) < limit
+
+	if len(s.kvs) > 0 {
+		s.resumeAfter(s.kvs[len(s.kvs)-1].Key)
+	}
+}
+
+// KeyValue returns the key-value pair read by the last call to Next.
+func (s *Scanner) KeyValue() KeyValue {
+	return s.kvs[s.index-1]
+}
+
+// Err returns the error that stopped the Scanner, if any.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Continuation returns a token recording the position of the scan following
+// the last key-value pair returned by Next, which may be passed to ScanAll as
+// ScanOptions.Continuation to resume the scan from that position. The token is
+// an opaque byte slice that may be persisted.
+func (s *Scanner) Continuation() []byte {
+	var flags byte
+	if s.options.Reverse {
+		flags |= continuationReverse
+	}
+	if s.exhausted && s.index == len(s.kvs) {
+		flags |= continuationDone
+	}
+
+	if !s.positioned && flags & continuationDone == 0 {
+		return nil
+	}
+
+	return append([]byte{continuationVersion, flags}, s.last...)
+}
@@ -0,0 +1,277 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package fdb_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+// scanKeys is the number of keys written by newScanDatabase, all within
+// scanRange.
+const scanKeys = 1000
+
+var scanRange = fdb.KeyRange{Begin: fdb.Key("k"), End: fdb.Key("l")}
+
+// newScanDatabase returns an in-memory database holding scanKeys keys, with a
+// shard boundary (as read by LocalityGetBoundaryKeys) every shard keys.
+func newScanDatabase(t *testing.T, shard int) fdb.Database {
+	db := memdb.New()
+
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		for i := 0; i < scanKeys; i++ {
+			k := fdb.Key(fmt.Sprintf("k%04d", i))
+			tr.Set(k, []byte(fmt.Sprintf("v%d", i)))
+			if i % shard == 0 {
+				tr.Set(append(fdb.Key("\xFF/keyServers/"), k...), nil)
+			}
+		}
+		/* The shard following the keys, without which the end of the range
+		/* read by LocalityGetBoundaryKeys would resolve past the last key */
+		tr.Set(append(fdb.Key("\xFF/keyServers/"), scanRange.End.FDBKey()...), nil)
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if ks, e := db.LocalityGetBoundaryKeys(scanRange, 0, 0); e != nil || len(ks) != (scanKeys + shard - 1) / shard {
+		t.Fatalf("LocalityGetBoundaryKeys returned %d boundaries (%v)", len(ks), e)
+	}
+
+	return db
+}
+
+// waitGoroutines waits for the number of running goroutines to fall to n,
+// failing the test if it does not within a second.
+func waitGoroutines(t *testing.T, n int) {
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Errorf("%d goroutines left running", runtime.NumGoroutine()-n)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestParallelScan(t *testing.T) {
+	db := newScanDatabase(t, 37).WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.2})
+
+	tests := []struct {
+		workers int
+		ordered bool
+	}{
+		{1, false},
+		{4, false},
+		{64, false},
+		{1, true},
+		{4, true},
+		{64, true},
+	}
+
+	for _, tt := range tests {
+		scan := fdb.ParallelScan
+		if tt.ordered {
+			scan = fdb.ParallelScanOrdered
+		}
+
+		seen := make(map[string]int)
+		var last fdb.Key
+		e := scan(db, scanRange, tt.workers, func(kv fdb.KeyValue) error {
+			seen[string(kv.Key)]++
+			if tt.ordered && bytes.Compare(kv.Key, last) <= 0 {
+				return fmt.Errorf("%s read after %s", kv.Key, last)
+			}
+			last = kv.Key
+			return nil
+		})
+		if e != nil {
+			t.Errorf("%d workers (ordered %v): %v", tt.workers, tt.ordered, e)
+			continue
+		}
+
+		for i := 0; i < scanKeys; i++ {
+			if k := fmt.Sprintf("k%04d", i); seen[k] != 1 {
+				t.Errorf("%d workers (ordered %v): %s read %d times", tt.workers, tt.ordered, k, seen[k])
+			}
+		}
+		if len(seen) != scanKeys {
+			t.Errorf("%d workers (ordered %v): read %d keys, expected %d", tt.workers, tt.ordered, len(seen), scanKeys)
+		}
+	}
+}
+
+func TestParallelScanError(t *testing.T) {
+	db := newScanDatabase(t, 10)
+	errStop := errors.New("stop")
+
+	for _, ordered := range []bool{false, true} {
+		scan := fdb.ParallelScan
+		if ordered {
+			scan = fdb.ParallelScanOrdered
+		}
+
+		before := runtime.NumGoroutine()
+
+		var calls int
+		e := scan(db, scanRange, 8, func(kv fdb.KeyValue) error {
+			if calls++; calls == 25 {
+				return errStop
+			}
+			return nil
+		})
+		if e != errStop {
+			t.Errorf("ordered %v: returned %v, expected %v", ordered, e, errStop)
+		}
+		if calls != 25 {
+			t.Errorf("ordered %v: fn called %d times after returning an error", ordered, calls-25)
+		}
+
+		waitGoroutines(t, before)
+	}
+}
+
+func TestParallelScanRetryPolicy(t *testing.T) {
+	/* Every read fails with a retryable error */
+	faulty := newScanDatabase(t, 10).WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 1})
+
+	var retries int
+	db := faulty.WithRetryPolicy(fdb.RetryPolicy{
+		MaxAttempts: 3,
+		OnRetry: func(int, error) { retries++ },
+	})
+	e := fdb.ParallelScan(db, scanRange, 4, func(fdb.KeyValue) error { return nil })
+	if !fdb.IsRetryable(e) {
+		t.Errorf("returned %v, expected an injected retryable error", e)
+	}
+	if retries != 2 {
+		t.Errorf("boundary keys read retried %d times, expected 2", retries)
+	}
+}
+
+func TestScanSplitRetryPolicy(t *testing.T) {
+	/* Every read fails with a retryable error */
+	faulty := newScanDatabase(t, 10).WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 1})
+
+	var retries int
+	db := faulty.WithRetryPolicy(fdb.RetryPolicy{
+		MaxAttempts: 3,
+		OnRetry: func(int, error) { retries++ },
+	})
+	n, e := fdb.ScanSplit(db, scanRange)
+	if !fdb.IsRetryable(e) {
+		t.Errorf("MaxAttempts: returned %v, expected an injected retryable error", e)
+	}
+	if n != 0 || retries != 2 {
+		t.Errorf("MaxAttempts: read %d keys in %d retries, expected 0 keys in 2", n, retries)
+	}
+
+	db = faulty.WithRetryPolicy(fdb.RetryPolicy{MaxElapsed: 50 * time.Millisecond})
+	start := time.Now()
+	if _, e := fdb.ScanSplit(db, scanRange); e == nil {
+		t.Errorf("MaxElapsed: returned no error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("MaxElapsed: retried for %v", d)
+	}
+
+	/* Without faults, the whole split is read */
+	if n, e := fdb.ScanSplit(newScanDatabase(t, 10).WithRetryPolicy(fdb.RetryPolicy{MaxAttempts: 1}), scanRange); n != scanKeys || e != nil {
+		t.Errorf("read %d keys (%v), expected %d", n, e, scanKeys)
+	}
+}
+
+func TestScanSplitProgress(t *testing.T) {
+	/* Values large enough that the split is read in many batches, any of
+	/* which may fail */
+	mem := memdb.New()
+	_, e := mem.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		for i := 0; i < scanKeys; i++ {
+			tr.Set(fdb.Key(fmt.Sprintf("k%04d", i)), bytes.Repeat([]byte{'v'}, 1000))
+		}
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	var retries int
+	db := mem.WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.3}).WithRetryPolicy(fdb.RetryPolicy{
+		MaxAttempts: 2,
+		OnRetry: func(int, error) { retries++ },
+	})
+
+	/* More reads fail than the policy allows attempts, but never twice
+	/* in a row before the split is advanced */
+	n, e := fdb.ScanSplit(db, scanRange)
+	if n != scanKeys || e != nil {
+		t.Errorf("read %d keys (%v), expected %d", n, e, scanKeys)
+	}
+	if retries <= 2 {
+		t.Errorf("split read with %d retries, expected more than the policy allows", retries)
+	}
+}
+
+func TestScanAllResume(t *testing.T) {
+	db := newScanDatabase(t, scanKeys).WithFaultInjection(fdb.FaultInjection{Seed: 1, Probability: 0.3})
+
+	for _, reverse := range []bool{false, true} {
+		var keys []string
+		var token []byte
+
+		/* Abandon each Scanner after 150 keys, resuming from its token */
+		for n := 150; n == 150; {
+			s := fdb.ScanAll(db, scanRange, fdb.ScanOptions{Reverse: reverse, TransactionLimit: 100, Continuation: token})
+			for n = 0; n < 150 && s.Next(); n++ {
+				keys = append(keys, string(s.KeyValue().Key))
+			}
+			if e := s.Err(); e != nil {
+				t.Fatalf("reverse %v: %v", reverse, e)
+			}
+			token = s.Continuation()
+		}
+
+		if len(keys) != scanKeys {
+			t.Errorf("reverse %v: read %d keys, expected %d", reverse, len(keys), scanKeys)
+			continue
+		}
+		for i, k := range keys {
+			j := i
+			if reverse {
+				j = scanKeys - 1 - i
+			}
+			if expected := fmt.Sprintf("k%04d", j); k != expected {
+				t.Errorf("reverse %v: read %s at position %d, expected %s", reverse, k, i, expected)
+				break
+			}
+		}
+	}
+}
@@ -21,6 +21,10 @@
 
 package fdb
 
+import (
+	"context"
+)
+
 // Snapshot is a handle to a FoundationDB transaction snapshot, suitable for
 // performing snapshot reads. Snapshot reads offer a more relaxed isolation
 // level than FoundationDB's default serializable isolation, reducing
@@ -52,6 +56,26 @@ func (s Snapshot) ReadTransact(f func (ReadTransaction) (interface{}, error)) (r
 	return
 }
 
+// ReadTransactContext is like ReadTransact, but returns ctx.Err() without
+// calling the function if ctx is already done. If ctx becomes done while the
+// function is running, the underlying transaction is cancelled and ctx.Err()
+// is returned wrapped together with the error returned or panicked by the
+// function.
+func (s Snapshot) ReadTransactContext(ctx context.Context, f func(ReadTransaction) (interface{}, error)) (r interface{}, e error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	stop := context.AfterFunc(ctx, Transaction{s.transaction}.Cancel)
+	defer stop()
+
+	r, e = s.ReadTransact(f)
+	if e != nil && ctx.Err() != nil {
+		return nil, contextError(ctx, e)
+	}
+	return
+}
+
 // Snapshot returns the receiver and allows Snapshot to satisfy the
 // ReadTransaction interface.
 func (s Snapshot) Snapshot() Snapshot {
@@ -60,12 +84,12 @@ func (s Snapshot) Snapshot() Snapshot {
 
 // Get is equivalent to (Transaction).Get, performed as a snapshot read.
 func (s Snapshot) Get(key KeyConvertible) FutureByteSlice {
-	return s.get(key.FDBKey(), 1)
+	return s.get(key.FDBKey(), true)
 }
 
 // GetKey is equivalent to (Transaction).GetKey, performed as a snapshot read.
 func (s Snapshot) GetKey(sel Selectable) FutureKey {
-	return s.getKey(sel.FDBKeySelector(), 1)
+	return s.getKey(sel.FDBKeySelector(), true)
 }
 
 // GetRange is equivalent to (Transaction).GetRange, performed as a snapshot
@@ -80,6 +104,17 @@ func (s Snapshot) GetReadVersion() FutureInt64 {
 	return s.getReadVersion()
 }
 
+// GetEstimatedRangeSizeBytes is equivalent to
+// (Transaction).GetEstimatedRangeSizeBytes.
+func (s Snapshot) GetEstimatedRangeSizeBytes(r ExactRange) FutureInt64 {
+	return s.getEstimatedRangeSizeBytes(r)
+}
+
+// GetRangeSplitPoints is equivalent to (Transaction).GetRangeSplitPoints.
+func (s Snapshot) GetRangeSplitPoints(r ExactRange, chunkSize int64) FutureKeyArray {
+	return s.getRangeSplitPoints(r, chunkSize)
+}
+
 // GetDatabase returns a handle to the database with which this snapshot is
 // interacting.
 func (s Snapshot) GetDatabase() Database {
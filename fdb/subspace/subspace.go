@@ -46,6 +46,10 @@ type Subspace interface {
 	// valid tuple.TupleElement, Sub will panic.
 	Sub(el ...tuple.TupleElement) Subspace
 
+	// SubE is like Sub, but returns an error (rather than panicking) if any of
+	// the elements are not a valid tuple.TupleElement.
+	SubE(el ...tuple.TupleElement) (Subspace, error)
+
 	// Bytes returns the literal bytes of the prefix of this Subspace.
 	Bytes() []byte
 
@@ -53,6 +57,10 @@ type Subspace interface {
 	// Subspace prepended.
 	Pack(t tuple.Tuple) fdb.Key
 
+	// PackE is like Pack, but returns an error (rather than panicking) if the
+	// Tuple cannot be encoded, as described by (tuple.Tuple).PackWithError.
+	PackE(t tuple.Tuple) (fdb.Key, error)
+
 	// Unpack returns the Tuple encoded by the given key with the prefix of this
 	// Subspace removed. Unpack will return an error if the key is not in this
 	// Subspace or does not encode a well-formed Tuple.
@@ -99,6 +107,14 @@ func (s subspace) Sub(el ...tuple.TupleElement) Subspace {
 	return subspace{concat(s.Bytes(), tuple.Tuple(el).Pack()...)}
 }
 
+func (s subspace) SubE(el ...tuple.TupleElement) (Subspace, error) {
+	p, e := tuple.Tuple(el).PackWithError()
+	if e != nil {
+		return nil, e
+	}
+	return subspace{concat(s.Bytes(), p...)}, nil
+}
+
 func (s subspace) Bytes() []byte {
 	return s.b
 }
@@ -107,6 +123,14 @@ func (s subspace) Pack(t tuple.Tuple) fdb.Key {
 	return fdb.Key(concat(s.b, t.Pack()...))
 }
 
+func (s subspace) PackE(t tuple.Tuple) (fdb.Key, error) {
+	p, e := t.PackWithError()
+	if e != nil {
+		return nil, e
+	}
+	return fdb.Key(concat(s.b, p...)), nil
+}
+
 func (s subspace) Unpack(k fdb.KeyConvertible) (tuple.Tuple, error) {
 	key := k.FDBKey()
 	if !bytes.HasPrefix(key, s.b) {
@@ -0,0 +1,99 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package subspace_test
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/FoundationDB/fdb-go/fdb/subspace"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+var huge = new(big.Int).Lsh(big.NewInt(1), 8*255)
+
+// packTest is a tuple, with the error expected when packing it (if any) and,
+// for an ErrUnsupportedType, the index of the unsupported element.
+type packTest struct {
+	t tuple.Tuple
+	err string
+	index int
+}
+
+var packTests = []packTest{
+	{tuple.Tuple{}, "", -1},
+	{tuple.Tuple{1, "b", tuple.Tuple{nil, 2.5}}, "", -1},
+	{tuple.Tuple{1, struct{}{}}, "unencodable element at index 1 ({}, type struct {})", 1},
+	{tuple.Tuple{"a", tuple.Tuple{1, struct{}{}}, 3}, "unencodable element at index 1 ({}, type struct {})", 1},
+	{tuple.Tuple{[]int{1}}, "unencodable element at index 0 ([1], type []int)", 0},
+	{tuple.Tuple{"a", "b", huge}, "integer at index 2 is too large to encode (magnitude of 256 bytes, at most 255 allowed)", -1},
+	{tuple.Tuple{"a", tuple.IncompleteVersionstamp(0)}, "tuple contains an incomplete versionstamp (use PackWithVersionstamp)", -1},
+}
+
+// checkError checks that e is the error expected for packing tt.t.
+func checkError(t *testing.T, name string, tt packTest, e error) {
+	if tt.err == "" {
+		if e != nil {
+			t.Errorf("%s(%v): %v", name, tt.t, e)
+		}
+		return
+	}
+	if e == nil || e.Error() != tt.err {
+		t.Errorf("%s(%v) returned %v, expected %q", name, tt.t, e, tt.err)
+	}
+	var ut tuple.ErrUnsupportedType
+	if errors.As(e, &ut) != (tt.index >= 0) || (tt.index >= 0 && ut.Index != tt.index) {
+		t.Errorf("%s(%v) returned %#v, expected ErrUnsupportedType at index %d", name, tt.t, e, tt.index)
+	}
+}
+
+func TestPackE(t *testing.T) {
+	s := subspace.Sub("prefix")
+
+	for _, tt := range packTests {
+		k, e := s.PackE(tt.t)
+		checkError(t, "PackE", tt, e)
+		if e == nil && !bytes.Equal(k, s.Pack(tt.t)) {
+			t.Errorf("PackE(%v) = %x, expected %x", tt.t, k, s.Pack(tt.t))
+		}
+		if e != nil && k != nil {
+			t.Errorf("PackE(%v) returned %x with an error", tt.t, k)
+		}
+	}
+}
+
+func TestSubE(t *testing.T) {
+	s := subspace.Sub("prefix")
+
+	for _, tt := range packTests {
+		sub, e := s.SubE(tt.t...)
+		checkError(t, "SubE", tt, e)
+		if e == nil && !bytes.Equal(sub.Bytes(), s.Sub(tt.t...).Bytes()) {
+			t.Errorf("SubE(%v) has prefix %x, expected %x", tt.t, sub.Bytes(), s.Sub(tt.t...).Bytes())
+		}
+		if e != nil && sub != nil {
+			t.Errorf("SubE(%v) returned %v with an error", tt.t, sub)
+		}
+	}
+}
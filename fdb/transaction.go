@@ -21,11 +21,10 @@
 
 package fdb
 
-/*
- #define FDB_API_VERSION 200
- #include <foundationdb/fdb_c.h>
-*/
-import "C"
+import (
+	"context"
+	"sync"
+)
 
 // A ReadTransaction can asynchronously read from a FoundationDB
 // database. Transaction and Snapshot both satisfy the ReadTransaction
@@ -38,6 +37,8 @@ type ReadTransaction interface {
 	GetKey(sel Selectable) FutureKey
 	GetRange(r Range, options RangeOptions) RangeResult
 	GetReadVersion() FutureInt64
+	GetEstimatedRangeSizeBytes(r ExactRange) FutureInt64
+	GetRangeSplitPoints(r ExactRange, chunkSize int64) FutureKeyArray
 	GetDatabase() Database
 	Snapshot() Snapshot
 
@@ -68,8 +69,13 @@ type Transaction struct {
 }
 
 type transaction struct {
-	ptr *C.FDBTransaction
+	backend TransactionBackend
 	db Database
+
+	// mu guards mutationErr, the error with which Commit fails because a
+	// mutation could not be performed at the selected API version
+	mu sync.Mutex
+	mutationErr error
 }
 
 // TransactionOptions is a handle with which to set options that affect a
@@ -80,13 +86,7 @@ type TransactionOptions struct {
 }
 
 func (opt TransactionOptions) setOpt(code int, param []byte) error {
-	return setOpt(func(p *C.uint8_t, pl C.int) C.fdb_error_t {
-		return C.fdb_transaction_set_option(opt.transaction.ptr, C.FDBTransactionOption(code), p, pl)
-	}, param)
-}
-
-func (t *transaction) destroy() {
-	C.fdb_transaction_destroy(t.ptr)
+	return opt.transaction.backend.SetOption(code, param)
 }
 
 // GetDatabase returns a handle to the database with which this transaction is
@@ -136,6 +136,48 @@ func (t Transaction) ReadTransact(f func(ReadTransaction) (interface{}, error))
 	return
 }
 
+// TransactContext is like Transact, but returns ctx.Err() without calling the
+// function if ctx is already done. If ctx becomes done while the function is
+// running, the Transaction is cancelled (see (Transaction).Cancel) and
+// ctx.Err() is returned wrapped together with the error returned or panicked
+// by the function.
+//
+// Note that cancelling the Transaction affects any enclosing transactional
+// functions that share it.
+func (t Transaction) TransactContext(ctx context.Context, f func(Transaction) (interface{}, error)) (r interface{}, e error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	stop := context.AfterFunc(ctx, t.Cancel)
+	defer stop()
+
+	r, e = t.Transact(f)
+	if e != nil && ctx.Err() != nil {
+		return nil, contextError(ctx, e)
+	}
+	return
+}
+
+// ReadTransactContext is like ReadTransact, but returns ctx.Err() without
+// calling the function if ctx is already done. See
+// (Transaction).TransactContext for details of how the Transaction is
+// cancelled and the error that is returned.
+func (t Transaction) ReadTransactContext(ctx context.Context, f func(ReadTransaction) (interface{}, error)) (r interface{}, e error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	stop := context.AfterFunc(ctx, t.Cancel)
+	defer stop()
+
+	r, e = t.ReadTransact(f)
+	if e != nil && ctx.Err() != nil {
+		return nil, contextError(ctx, e)
+	}
+	return
+}
+
 // Cancel cancels a transaction. All pending or future uses of the transaction
 // will encounter an error. The Transaction object may be reused after calling
 // (Transaction).Reset.
@@ -153,7 +195,7 @@ func (t Transaction) ReadTransact(f func(ReadTransaction) (interface{}, error))
 // error, the commit may have occurred or may occur in the future. This can make
 // it more difficult to reason about the order in which transactions occur.
 func (t Transaction) Cancel() {
-	C.fdb_transaction_cancel(t.ptr)
+	t.backend.Cancel()
 }
 
 // (Infrequently used) SetReadVersion sets the database version that the transaction will read from
@@ -161,7 +203,7 @@ func (t Transaction) Cancel() {
 // is used (the transaction’s reads will be causally consistent only if the
 // provided read version has that property).
 func (t Transaction) SetReadVersion(version int64) {
-	C.fdb_transaction_set_read_version(t.ptr, C.int64_t(version))
+	t.backend.SetReadVersion(version)
 }
 
 // Snapshot returns a Snapshot object, suitable for performing snapshot
@@ -183,7 +225,11 @@ func (t Transaction) Snapshot() Snapshot {
 // Typical code will not use OnError directly. (Database).Transact uses
 // OnError internally to implement a correct retry loop.
 func (t Transaction) OnError(e Error) FutureNil {
-	return &futureNil{newFuture(C.fdb_transaction_on_error(t.ptr, C.fdb_error_t(e.Code)))}
+	/* A retryable error resets the transaction, discarding its mutations */
+	if IsRetryable(e) {
+		t.setMutationError(nil)
+	}
+	return t.backend.OnError(e)
 }
 
 // Commit attempts to commit the modifications made in the transaction to the
@@ -196,8 +242,15 @@ func (t Transaction) OnError(e Error) FutureNil {
 // be unable to determine whether a transaction succeeded. For more information,
 // see
 // https://foundationdb.com/documentation/developer-guide.html#developer-guide-unknown-results.
+//
+// If a mutation was not performed because it requires a later API version than
+// has been selected, the returned future fails with an error wrapping
+// ErrAPIVersionNotSupported, and nothing is committed.
 func (t Transaction) Commit() FutureNil {
-	return &futureNil{newFuture(C.fdb_transaction_commit(t.ptr))}
+	if e := t.mutationError(); e != nil {
+		return NewFutureNil(nil, func() error { return e }, nil)
+	}
+	return t.backend.Commit()
 }
 
 // Watch creates a watch and returns a FutureNil that will become ready when the
@@ -224,29 +277,39 @@ func (t Transaction) Commit() FutureNil {
 // transaction that creates it, any watch that is no longer needed should be
 // cancelled by calling (FutureNil).Cancel on its returned future.
 func (t Transaction) Watch(key KeyConvertible) FutureNil {
-	kb := key.FDBKey()
-	return &futureNil{newFuture(C.fdb_transaction_watch(t.ptr, byteSliceToPtr(kb), C.int(len(kb))))}
+	return t.backend.Watch(key.FDBKey())
+}
+
+// GetVersionstamp returns a future that will become ready with the versionstamp
+// of the transaction once it has been committed. The versionstamp is the 10
+// bytes used by SetVersionstampedKey and SetVersionstampedValue: the committed
+// version of the transaction (8 bytes, big-endian) followed by its order within
+// the batch of transactions committed at that version (2 bytes, big-endian).
+//
+// GetVersionstamp must be called before Commit; waiting on the returned future
+// before the transaction has been committed will block indefinitely. If the
+// transaction fails to commit, the future fails with the same error, and if the
+// transaction is read-only, it fails with ErrNoCommitVersion.
+//
+// GetVersionstamp requires API version 410 or later.
+func (t Transaction) GetVersionstamp() FutureKey {
+	return t.backend.GetVersionstamp()
 }
 
-func (t *transaction) get(key []byte, snapshot int) FutureByteSlice {
-	return &futureByteSlice{future: newFuture(C.fdb_transaction_get(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(snapshot)))}
+func (t *transaction) get(key []byte, snapshot bool) FutureByteSlice {
+	return t.backend.Get(key, snapshot)
 }
 
 // Get returns the (future) value associated with the specified key. The read is
 // performed asynchronously and does not block the calling goroutine. The future
 // will become ready when the read is complete.
 func (t Transaction) Get(key KeyConvertible) FutureByteSlice {
-	return t.get(key.FDBKey(), 0)
+	return t.get(key.FDBKey(), false)
 }
 
-func (t *transaction) doGetRange(r Range, options RangeOptions, snapshot bool, iteration int) futureKeyValueArray {
+func (t *transaction) doGetRange(r Range, options RangeOptions, snapshot bool, iteration int) FutureKeyValueArray {
 	begin, end := r.FDBRangeKeySelectors()
-	bsel := begin.FDBKeySelector()
-	esel := end.FDBKeySelector()
-	bkey := bsel.Key.FDBKey()
-	ekey := esel.Key.FDBKey()
-
-	return futureKeyValueArray{newFuture(C.fdb_transaction_get_range(t.ptr, byteSliceToPtr(bkey), C.int(len(bkey)), C.fdb_bool_t(boolToInt(bsel.OrEqual)), C.int(bsel.Offset), byteSliceToPtr(ekey), C.int(len(ekey)), C.fdb_bool_t(boolToInt(esel.OrEqual)), C.int(esel.Offset), C.int(options.Limit), C.int(0), C.FDBStreamingMode(options.Mode-1), C.int(iteration), C.fdb_bool_t(boolToInt(snapshot)), C.fdb_bool_t(boolToInt(options.Reverse))))}
+	return t.backend.GetRange(begin.FDBKeySelector(), end.FDBKeySelector(), options, snapshot, iteration)
 }
 
 func (t *transaction) getRange(r Range, options RangeOptions, snapshot bool) RangeResult {
@@ -257,7 +320,7 @@ func (t *transaction) getRange(r Range, options RangeOptions, snapshot bool) Ran
 		sr: SelectorRange{begin, end},
 		options: options,
 		snapshot: snapshot,
-		f: &f,
+		f: f,
 	}
 }
 
@@ -271,7 +334,7 @@ func (t Transaction) GetRange(r Range, options RangeOptions) RangeResult {
 }
 
 func (t *transaction) getReadVersion() FutureInt64 {
-	return &futureInt64{newFuture(C.fdb_transaction_get_read_version(t.ptr))}
+	return t.backend.GetReadVersion()
 }
 
 // (Infrequently used) GetReadVersion returns the (future) transaction read version. The read is
@@ -281,20 +344,61 @@ func (t Transaction) GetReadVersion() FutureInt64 {
 	return t.getReadVersion()
 }
 
+func (t *transaction) getEstimatedRangeSizeBytes(r ExactRange) FutureInt64 {
+	begin, end := r.FDBRangeKeys()
+	return t.backend.GetEstimatedRangeSizeBytes(begin.FDBKey(), end.FDBKey())
+}
+
+// GetEstimatedRangeSizeBytes returns the (future) estimated size, in bytes, of
+// the key-value pairs in the provided range, as stored by the database. The
+// estimate is computed from sampled statistics and is not exact: for ranges
+// smaller than a few megabytes it may be inaccurate (and is often zero).
+//
+// GetEstimatedRangeSizeBytes requires API version 630 or later.
+func (t Transaction) GetEstimatedRangeSizeBytes(r ExactRange) FutureInt64 {
+	return t.getEstimatedRangeSizeBytes(r)
+}
+
+func (t *transaction) getRangeSplitPoints(r ExactRange, chunkSize int64) FutureKeyArray {
+	begin, end := r.FDBRangeKeys()
+	return t.backend.GetRangeSplitPoints(begin.FDBKey(), end.FDBKey(), chunkSize)
+}
+
+// GetRangeSplitPoints returns (future) keys that split the provided range into
+// contiguous chunks of approximately chunkSize bytes each, as estimated from the
+// same sampled statistics as GetEstimatedRangeSizeBytes. The keys begin with
+// the beginning of the range and end with its end, so n+1 keys describe n
+// chunks. Reading each chunk separately (for example, with a TargetBytes
+// option of about chunkSize) allows a large range to be paginated by size
+// rather than by row count.
+//
+// GetRangeSplitPoints requires API version 700 or later.
+func (t Transaction) GetRangeSplitPoints(r ExactRange, chunkSize int64) FutureKeyArray {
+	return t.getRangeSplitPoints(r, chunkSize)
+}
+
+// GetApproximateSize returns the (future) approximate size, in bytes, of the
+// commit request for this transaction, including its mutations and conflict
+// ranges. It may be used to keep a transaction below the transaction size
+// limit.
+//
+// GetApproximateSize requires API version 620 or later.
+func (t Transaction) GetApproximateSize() FutureInt64 {
+	return t.backend.GetApproximateSize()
+}
+
 // Set associated the given key and value, overwriting any previous association
 // with key. Set returns immediately, having modified the snapshot of the
 // database represented by the transaction.
 func (t Transaction) Set(key KeyConvertible, value []byte) {
-	kb := key.FDBKey()
-	C.fdb_transaction_set(t.ptr, byteSliceToPtr(kb), C.int(len(kb)), byteSliceToPtr(value), C.int(len(value)))
+	t.backend.Set(key.FDBKey(), value)
 }
 
 // Clear removes the specified key (and any associated value), if it
 // exists. Clear returns immediately, having modified the snapshot of the
 // database represented by the transaction.
 func (t Transaction) Clear(key KeyConvertible) {
-	kb := key.FDBKey()
-	C.fdb_transaction_clear(t.ptr, byteSliceToPtr(kb), C.int(len(kb)))
+	t.backend.Clear(key.FDBKey())
 }
 
 // ClearRange removes all keys k such that begin <= k < end, and their
@@ -302,9 +406,7 @@ func (t Transaction) Clear(key KeyConvertible) {
 // snapshot of the database represented by the transaction.
 func (t Transaction) ClearRange(er ExactRange) {
 	begin, end := er.FDBRangeKeys()
-	bkb := begin.FDBKey()
-	ekb := end.FDBKey()
-	C.fdb_transaction_clear_range(t.ptr, byteSliceToPtr(bkb), C.int(len(bkb)), byteSliceToPtr(ekb), C.int(len(ekb)))
+	t.backend.ClearRange(begin.FDBKey(), end.FDBKey())
 }
 
 // (Infrequently used) GetCommittedVersion returns the version number at which a
@@ -315,33 +417,19 @@ func (t Transaction) ClearRange(er ExactRange) {
 // transaction which reads keys and then sets them to their current values may
 // be optimized to a read-only transaction.
 func (t Transaction) GetCommittedVersion() (int64, error) {
-	var version C.int64_t
-
-	if err := C.fdb_transaction_get_committed_version(t.ptr, &version); err != 0 {
-		return 0, Error{int(err)}
-	}
-
-	return int64(version), nil
+	return t.backend.GetCommittedVersion()
 }
 
 // Reset rolls back a transaction, completely resetting it to its initial
 // state. This is logically equivalent to destroying the transaction and
 // creating a new one.
 func (t Transaction) Reset() {
-	C.fdb_transaction_reset(t.ptr)
-}
-
-func boolToInt(b bool) int {
-	if b {
-		return 1
-	} else {
-		return 0
-	}
+	t.setMutationError(nil)
+	t.backend.Reset()
 }
 
-func (t *transaction) getKey(sel KeySelector, snapshot int) FutureKey {
-	key := sel.Key.FDBKey()
-	return &futureKey{future: newFuture(C.fdb_transaction_get_key(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(boolToInt(sel.OrEqual)), C.int(sel.Offset), C.fdb_bool_t(snapshot)))}
+func (t *transaction) getKey(sel KeySelector, snapshot bool) FutureKey {
+	return t.backend.GetKey(sel, snapshot)
 }
 
 // GetKey returns the future key referenced by the provided key selector. The
@@ -354,22 +442,36 @@ func (t *transaction) getKey(sel KeySelector, snapshot int) FutureKey {
 // (TransactionOptions).SetReadYourWritesDisable will avoid both the caching and
 // the increased network bandwidth.
 func (t Transaction) GetKey(sel Selectable) FutureKey {
-	return t.getKey(sel.FDBKeySelector(), 0)
+	return t.getKey(sel.FDBKeySelector(), false)
 }
 
 func (t Transaction) atomicOp(key []byte, param []byte, code int) {
-	C.fdb_transaction_atomic_op(t.ptr, byteSliceToPtr(key), C.int(len(key)), byteSliceToPtr(param), C.int(len(param)), C.FDBMutationType(code))
+	if e := requireAPIVersion(mutationAPIVersions[code]); e != nil {
+		t.setMutationError(e)
+		return
+	}
+	t.backend.AtomicOp(key, param, code)
 }
 
-func addConflictRange(t *transaction, er ExactRange, crtype conflictRangeType) error {
-	begin, end := er.FDBRangeKeys()
-	bkb := begin.FDBKey()
-	ekb := end.FDBKey()
-	if err := C.fdb_transaction_add_conflict_range(t.ptr, byteSliceToPtr(bkb), C.int(len(bkb)), byteSliceToPtr(ekb), C.int(len(ekb)), C.FDBConflictRangeType(crtype)); err != 0 {
-		return Error{int(err)}
+func (t *transaction) mutationError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.mutationErr
+}
+
+// setMutationError records e as the error with which Commit fails, unless an
+// earlier mutation has already failed.
+func (t *transaction) setMutationError(e error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if e == nil || t.mutationErr == nil {
+		t.mutationErr = e
 	}
+}
 
-	return nil
+func addConflictRange(t *transaction, er ExactRange, crtype ConflictRangeType) error {
+	begin, end := er.FDBRangeKeys()
+	return t.backend.AddConflictRange(begin.FDBKey(), end.FDBKey(), crtype == ConflictRangeTypeWrite)
 }
 
 // AddReadConflictRange adds a range of keys to the transaction’s read conflict
@@ -380,7 +482,7 @@ func addConflictRange(t *transaction, er ExactRange, crtype conflictRangeType) e
 // For more information on conflict ranges, see
 // https://foundationdb.com/documentation/developer-guide.html#conflict-ranges.
 func (t Transaction) AddReadConflictRange(er ExactRange) error {
-	return addConflictRange(t.transaction, er, conflictRangeTypeRead)
+	return addConflictRange(t.transaction, er, ConflictRangeTypeRead)
 }
 
 func copyAndAppend(orig []byte, b byte) []byte {
@@ -396,7 +498,7 @@ func copyAndAppend(orig []byte, b byte) []byte {
 // For more information on conflict ranges, see
 // https://foundationdb.com/documentation/developer-guide.html#conflict-ranges.
 func (t Transaction) AddReadConflictKey(key KeyConvertible) error {
-	return addConflictRange(t.transaction, KeyRange{key, Key(copyAndAppend(key.FDBKey(), 0x00))}, conflictRangeTypeRead)
+	return addConflictRange(t.transaction, KeyRange{key, Key(copyAndAppend(key.FDBKey(), 0x00))}, ConflictRangeTypeRead)
 }
 
 // AddWriteConflictRange adds a range of keys to the transaction’s write
@@ -407,7 +509,7 @@ func (t Transaction) AddReadConflictKey(key KeyConvertible) error {
 // For more information on conflict ranges, see
 // https://foundationdb.com/documentation/developer-guide.html#conflict-ranges.
 func (t Transaction) AddWriteConflictRange(er ExactRange) error {
-	return addConflictRange(t.transaction, er, conflictRangeTypeWrite)
+	return addConflictRange(t.transaction, er, ConflictRangeTypeWrite)
 }
 
 // AddWriteConflictKey adds a key to the transaction’s write conflict ranges as
@@ -417,7 +519,7 @@ func (t Transaction) AddWriteConflictRange(er ExactRange) error {
 // For more information on conflict ranges, see
 // https://foundationdb.com/documentation/developer-guide.html#conflict-ranges.
 func (t Transaction) AddWriteConflictKey(key KeyConvertible) error {
-	return addConflictRange(t.transaction, KeyRange{key, Key(copyAndAppend(key.FDBKey(), 0x00))}, conflictRangeTypeWrite)
+	return addConflictRange(t.transaction, KeyRange{key, Key(copyAndAppend(key.FDBKey(), 0x00))}, ConflictRangeTypeWrite)
 }
 
 // Options returns a TransactionOptions instance suitable for setting options
@@ -427,8 +529,7 @@ func (t Transaction) Options() TransactionOptions {
 }
 
 func localityGetAddressesForKey(t *transaction, key KeyConvertible) FutureStringSlice {
-	kb := key.FDBKey()
-	return &futureStringSlice{newFuture(C.fdb_transaction_get_addresses_for_key(t.ptr, byteSliceToPtr(kb), C.int(len(kb))))}
+	return t.backend.GetAddressesForKey(key.FDBKey())
 }
 
 // LocalityGetAddressesForKey returns the (future) public network addresses of
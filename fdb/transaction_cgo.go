@@ -0,0 +1,202 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb
+
+/*
+ #include <foundationdb/fdb_c.h>
+
+ FDBFuture* go_transaction_get_versionstamp(FDBTransaction* tr) {
+ #if FDB_API_VERSION >= 410
+     return fdb_transaction_get_versionstamp(tr);
+ #else
+     return 0;
+ #endif
+ }
+
+ FDBFuture* go_transaction_get_approximate_size(FDBTransaction* tr) {
+ #if FDB_API_VERSION >= 620
+     return fdb_transaction_get_approximate_size(tr);
+ #else
+     return 0;
+ #endif
+ }
+
+ FDBFuture* go_transaction_get_estimated_range_size_bytes(FDBTransaction* tr, uint8_t const* begin_key_name, int begin_key_name_length, uint8_t const* end_key_name, int end_key_name_length) {
+ #if FDB_API_VERSION >= 630
+     return fdb_transaction_get_estimated_range_size_bytes(tr, begin_key_name, begin_key_name_length, end_key_name, end_key_name_length);
+ #else
+     return 0;
+ #endif
+ }
+
+ FDBFuture* go_transaction_get_range_split_points(FDBTransaction* tr, uint8_t const* begin_key_name, int begin_key_name_length, uint8_t const* end_key_name, int end_key_name_length, int64_t chunk_size) {
+ #if FDB_API_VERSION >= 700
+     return fdb_transaction_get_range_split_points(tr, begin_key_name, begin_key_name_length, end_key_name, end_key_name_length, chunk_size);
+ #else
+     return 0;
+ #endif
+ }
+*/
+import "C"
+
+// cTransaction is the TransactionBackend provided by the FoundationDB C
+// library.
+type cTransaction struct {
+	ptr *C.FDBTransaction
+}
+
+func (t *cTransaction) destroy() {
+	C.fdb_transaction_destroy(t.ptr)
+}
+
+func (t *cTransaction) SetOption(code int, param []byte) error {
+	return setOpt(func(p *C.uint8_t, pl C.int) C.fdb_error_t {
+		return C.fdb_transaction_set_option(t.ptr, C.FDBTransactionOption(code), p, pl)
+	}, param)
+}
+
+func (t *cTransaction) Cancel() {
+	C.fdb_transaction_cancel(t.ptr)
+}
+
+func (t *cTransaction) SetReadVersion(version int64) {
+	C.fdb_transaction_set_read_version(t.ptr, C.int64_t(version))
+}
+
+func (t *cTransaction) OnError(e Error) FutureNil {
+	return &futureNil{newFuture(C.fdb_transaction_on_error(t.ptr, C.fdb_error_t(e.Code)))}
+}
+
+func (t *cTransaction) Commit() FutureNil {
+	return &futureNil{newFuture(C.fdb_transaction_commit(t.ptr))}
+}
+
+func (t *cTransaction) Watch(key Key) FutureNil {
+	return &futureNil{newFuture(C.fdb_transaction_watch(t.ptr, byteSliceToPtr(key), C.int(len(key))))}
+}
+
+func (t *cTransaction) Get(key Key, snapshot bool) FutureByteSlice {
+	return &futureByteSlice{future: newFuture(C.fdb_transaction_get(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(boolToInt(snapshot))))}
+}
+
+func (t *cTransaction) GetRange(bsel, esel KeySelector, options RangeOptions, snapshot bool, iteration int) FutureKeyValueArray {
+	bkey := bsel.Key.FDBKey()
+	ekey := esel.Key.FDBKey()
+
+	return futureKeyValueArray{newFuture(C.fdb_transaction_get_range(t.ptr, byteSliceToPtr(bkey), C.int(len(bkey)), C.fdb_bool_t(boolToInt(bsel.OrEqual)), C.int(bsel.Offset), byteSliceToPtr(ekey), C.int(len(ekey)), C.fdb_bool_t(boolToInt(esel.OrEqual)), C.int(esel.Offset), C.int(options.Limit), C.int(options.TargetBytes), C.FDBStreamingMode(options.Mode-1), C.int(iteration), C.fdb_bool_t(boolToInt(snapshot)), C.fdb_bool_t(boolToInt(options.Reverse))))}
+}
+
+func (t *cTransaction) GetReadVersion() FutureInt64 {
+	return &futureInt64{newFuture(C.fdb_transaction_get_read_version(t.ptr))}
+}
+
+func (t *cTransaction) Set(key Key, value []byte) {
+	C.fdb_transaction_set(t.ptr, byteSliceToPtr(key), C.int(len(key)), byteSliceToPtr(value), C.int(len(value)))
+}
+
+func (t *cTransaction) Clear(key Key) {
+	C.fdb_transaction_clear(t.ptr, byteSliceToPtr(key), C.int(len(key)))
+}
+
+func (t *cTransaction) ClearRange(begin, end Key) {
+	C.fdb_transaction_clear_range(t.ptr, byteSliceToPtr(begin), C.int(len(begin)), byteSliceToPtr(end), C.int(len(end)))
+}
+
+func (t *cTransaction) GetCommittedVersion() (int64, error) {
+	var version C.int64_t
+
+	if err := C.fdb_transaction_get_committed_version(t.ptr, &version); err != 0 {
+		return 0, Error{int(err)}
+	}
+
+	return int64(version), nil
+}
+
+func (t *cTransaction) Reset() {
+	C.fdb_transaction_reset(t.ptr)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	} else {
+		return 0
+	}
+}
+
+func (t *cTransaction) GetKey(sel KeySelector, snapshot bool) FutureKey {
+	key := sel.Key.FDBKey()
+	return &futureKey{future: newFuture(C.fdb_transaction_get_key(t.ptr, byteSliceToPtr(key), C.int(len(key)), C.fdb_bool_t(boolToInt(sel.OrEqual)), C.int(sel.Offset), C.fdb_bool_t(boolToInt(snapshot))))}
+}
+
+func (t *cTransaction) AtomicOp(key Key, param []byte, code int) {
+	C.fdb_transaction_atomic_op(t.ptr, byteSliceToPtr(key), C.int(len(key)), byteSliceToPtr(param), C.int(len(param)), C.FDBMutationType(code))
+}
+
+func (t *cTransaction) AddConflictRange(begin, end Key, write bool) error {
+	crtype := ConflictRangeTypeRead
+	if write {
+		crtype = ConflictRangeTypeWrite
+	}
+
+	if err := C.fdb_transaction_add_conflict_range(t.ptr, byteSliceToPtr(begin), C.int(len(begin)), byteSliceToPtr(end), C.int(len(end)), C.FDBConflictRangeType(crtype)); err != 0 {
+		return Error{int(err)}
+	}
+
+	return nil
+}
+
+func (t *cTransaction) GetAddressesForKey(key Key) FutureStringSlice {
+	return &futureStringSlice{newFuture(C.fdb_transaction_get_addresses_for_key(t.ptr, byteSliceToPtr(key), C.int(len(key))))}
+}
+
+/* The C functions below are only available from the API version that
+/* introduced them, so are called through wrappers in the preamble; the
+/* runtime API version never exceeds the header version */
+
+func (t *cTransaction) GetVersionstamp() FutureKey {
+	if e := requireAPIVersion(410); e != nil {
+		return NewFutureKey(nil, func() (Key, error) { return nil, e }, nil)
+	}
+	return &futureKey{future: newFuture(C.go_transaction_get_versionstamp(t.ptr))}
+}
+
+func (t *cTransaction) GetEstimatedRangeSizeBytes(begin, end Key) FutureInt64 {
+	if e := requireAPIVersion(630); e != nil {
+		return NewFutureInt64(nil, func() (int64, error) { return 0, e }, nil)
+	}
+	return &futureInt64{newFuture(C.go_transaction_get_estimated_range_size_bytes(t.ptr, byteSliceToPtr(begin), C.int(len(begin)), byteSliceToPtr(end), C.int(len(end))))}
+}
+
+func (t *cTransaction) GetRangeSplitPoints(begin, end Key, chunkSize int64) FutureKeyArray {
+	if e := requireAPIVersion(700); e != nil {
+		return NewFutureKeyArray(nil, func() ([]Key, error) { return nil, e }, nil)
+	}
+	return &futureKeyArray{newFuture(C.go_transaction_get_range_split_points(t.ptr, byteSliceToPtr(begin), C.int(len(begin)), byteSliceToPtr(end), C.int(len(end)), C.int64_t(chunkSize)))}
+}
+
+func (t *cTransaction) GetApproximateSize() FutureInt64 {
+	if e := requireAPIVersion(620); e != nil {
+		return NewFutureInt64(nil, func() (int64, error) { return 0, e }, nil)
+	}
+	return &futureInt64{newFuture(C.go_transaction_get_approximate_size(t.ptr))}
+}
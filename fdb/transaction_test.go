@@ -0,0 +1,155 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package fdb_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/memdb"
+)
+
+func TestMutationAPIVersionGating(t *testing.T) {
+	fdb.SelectAPIVersion(t, 200)
+
+	db := memdb.New()
+	key := fdb.Key("key")
+
+	tests := []struct {
+		name string
+		mutate func(tr fdb.Transaction)
+	}{
+		{"SetVersionstampedKey", func(tr fdb.Transaction) { tr.SetVersionstampedKey(key, []byte("param\x00\x00")) }},
+		{"SetVersionstampedValue", func(tr fdb.Transaction) { tr.SetVersionstampedValue(key, []byte("param\x00\x00")) }},
+		{"ByteMin", func(tr fdb.Transaction) { tr.ByteMin(key, []byte("param")) }},
+		{"ByteMax", func(tr fdb.Transaction) { tr.ByteMax(key, []byte("param")) }},
+		{"CompareAndClear", func(tr fdb.Transaction) { tr.CompareAndClear(key, []byte("param")) }},
+	}
+
+	for _, tt := range tests {
+		tr, e := db.CreateTransaction()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		tt.mutate(tr)
+		tr.Set(fdb.Key("other"), []byte("value"))
+		if e := tr.Commit().Get(); !errors.Is(e, fdb.ErrAPIVersionNotSupported) {
+			t.Errorf("commit after %s at API version 200 returned %v, expected ErrAPIVersionNotSupported", tt.name, e)
+		}
+
+		/* Nothing is committed */
+		v, e := db.ReadTransact(func(rtr fdb.ReadTransaction) (interface{}, error) {
+			return rtr.Get(fdb.Key("other")).Get()
+		})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if v.([]byte) != nil {
+			t.Errorf("commit after %s at API version 200 wrote %q", tt.name, v)
+		}
+
+		/* Reset discards the mutation that was not performed */
+		tr.Reset()
+		if e := tr.Commit().Get(); e != nil {
+			t.Errorf("commit after %s and Reset returned %v", tt.name, e)
+		}
+	}
+
+	/* Database.Transact returns the error without retrying */
+	var calls int
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		calls++
+		tr.ByteMax(key, []byte("param"))
+		return nil, nil
+	})
+	if !errors.Is(e, fdb.ErrAPIVersionNotSupported) {
+		t.Errorf("Transact returned %v, expected ErrAPIVersionNotSupported", e)
+	}
+	if calls != 1 {
+		t.Errorf("function called %d times, expected 1", calls)
+	}
+}
+
+func TestRangeReadAheadWrites(t *testing.T) {
+	/* Chunks of a few key-value pairs each */
+	fdb.SetReadAheadChunkBytes(t, 50)
+
+	db := memdb.New()
+	_, e := db.Transact(func(tr fdb.Transaction) (interface{}, error) {
+		for i := 0; i < 500; i++ {
+			tr.Set(fdb.Key(fmt.Sprintf("k%04d", i)), []byte("v"))
+		}
+		return nil, nil
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	tr, e := db.CreateTransaction()
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	/* Writes of the transaction, which read ahead must see in every chunk */
+	tr.Set(fdb.Key("k0100x"), []byte("new"))
+	tr.Set(fdb.Key("k0499"), []byte("changed"))
+	tr.ClearRange(fdb.KeyRange{Begin: fdb.Key("k0200"), End: fdb.Key("k0250")})
+	tr.Set(fdb.Key("l"), []byte("outside"))
+
+	r := fdb.KeyRange{Begin: fdb.Key("k"), End: fdb.Key("l")}
+	for _, options := range []fdb.RangeOptions{
+		{ReadAhead: 4},
+		{ReadAhead: 4, Reverse: true},
+		{ReadAhead: 4, Limit: 123},
+		{ReadAhead: 2, Limit: 300, Reverse: true},
+	} {
+		serial := options
+		serial.ReadAhead = 0
+		expected, e := tr.GetRange(r, serial).GetSliceWithError()
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		var read []fdb.KeyValue
+		for kv, e := range tr.GetRange(r, options).All() {
+			if e != nil {
+				t.Fatal(e)
+			}
+			read = append(read, kv)
+		}
+
+		if len(read) != len(expected) {
+			t.Errorf("%+v: read %d key-value pairs, expected %d", options, len(read), len(expected))
+			continue
+		}
+		for i := range read {
+			if !bytes.Equal(read[i].Key, expected[i].Key) || !bytes.Equal(read[i].Value, expected[i].Value) {
+				t.Errorf("%+v: read %s=%s at position %d, expected %s=%s", options, read[i].Key, read[i].Value, i, expected[i].Key, expected[i].Value)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple
+
+import (
+	"math/big"
+)
+
+// Kind identifies the type of a tuple element being decoded by a Decoder.
+type Kind int
+
+const (
+	KindNil Kind = iota
+	KindBytes
+	KindString
+	KindTuple
+	KindInt
+	KindFloat
+	KindDouble
+	KindBool
+	KindUUID
+	KindVersionstamp
+)
+
+// A Decoder reads the elements of an encoded tuple one at a time, without
+// building a Tuple. Accessors return the element most recently read by Next,
+// and (unlike Unpack) do not allocate except to decode big integers or
+// strings containing escaped 0x00 bytes, so a Decoder is suited to scanning
+// large numbers of keys for a few elements each:
+//
+//	d := tuple.NewDecoder(kv.Key)
+//	for d.Next() {
+//		if i, ok := d.Int(); ok {
+//			sum += i
+//		}
+//	}
+//	if e := d.Err(); e != nil {
+//		return e
+//	}
+//
+// The accessors may only be called after Next has returned true. A Decoder
+// reports the same malformed input as Unpack, but only once it has read as far
+// as the malformed element.
+type Decoder struct {
+	b []byte
+	pos int
+	start int
+	nested bool
+	err error
+}
+
+// NewDecoder returns a Decoder reading the tuple encoded by b.
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{b: b}
+}
+
+// Reset discards the state of the Decoder and makes it read the tuple encoded
+// by b, so that a single Decoder may be reused for many keys.
+func (d *Decoder) Reset(b []byte) {
+	*d = Decoder{b: b}
+}
+
+// Next reads the next element of the tuple, returning false when there are no
+// more elements or the input is malformed (in which case Err returns the
+// error).
+func (d *Decoder) Next() bool {
+	d.start = d.pos
+	if d.err != nil || d.pos >= len(d.b) {
+		return false
+	}
+	if d.nested && isTerminator(d.b, d.pos) {
+		return false
+	}
+
+	end, e := elementEnd(d.b, d.pos, d.nested)
+	if e != nil {
+		d.err = e
+		return false
+	}
+	d.pos = end
+	return true
+}
+
+// Err returns the error that stopped the Decoder, or nil if the input has been
+// well-formed so far.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Raw returns the encoding of the current element (including its typecode),
+// which aliases the input of the Decoder.
+func (d *Decoder) Raw() []byte {
+	return d.b[d.start:d.pos]
+}
+
+// Kind returns the type of the current element.
+func (d *Decoder) Kind() Kind {
+	switch code := d.b[d.start]; {
+	case code == 0x00:
+		return KindNil
+	case code == 0x01:
+		return KindBytes
+	case code == 0x02:
+		return KindString
+	case code == 0x05:
+		return KindTuple
+	case 0x0b <= code && code <= 0x1d:
+		return KindInt
+	case code == 0x20:
+		return KindFloat
+	case code == 0x21:
+		return KindDouble
+	case code == 0x26, code == 0x27:
+		return KindBool
+	case code == 0x30:
+		return KindUUID
+	}
+	return KindVersionstamp
+}
+
+// Element returns the current element as it would appear in the Tuple returned
+// by Unpack.
+func (d *Decoder) Element() TupleElement {
+	return decodeElement(d.Raw(), false)
+}
+
+// Bytes returns the contents of the current element if it is a byte or unicode
+// string. The result aliases the input of the Decoder unless the string
+// contains escaped 0x00 bytes.
+func (d *Decoder) Bytes() ([]byte, bool) {
+	raw := d.Raw()
+	if raw[0] != 0x01 && raw[0] != 0x02 {
+		return nil, false
+	}
+	return unescape(raw[1:len(raw)-1], true), true
+}
+
+// Int returns the current element if it is an integer within the range of
+// int64.
+func (d *Decoder) Int() (int64, bool) {
+	raw := d.Raw()
+	if raw[0] < 0x0c || raw[0] > 0x1c {
+		return 0, false
+	}
+	i, u, ok := decodeSmallInt(raw)
+	return i, ok && u == 0
+}
+
+// Uint returns the current element if it is a non-negative integer within the
+// range of uint64.
+func (d *Decoder) Uint() (uint64, bool) {
+	raw := d.Raw()
+	if raw[0] < 0x14 || raw[0] > 0x1c {
+		return 0, false
+	}
+	return readBigEndian(raw[1:]), true
+}
+
+// BigInt returns the current element, which may be an integer of any size, as
+// a new *big.Int.
+func (d *Decoder) BigInt() (*big.Int, bool) {
+	switch el := d.Element().(type) {
+	case int64:
+		return big.NewInt(el), true
+	case uint64:
+		return new(big.Int).SetUint64(el), true
+	case *big.Int:
+		return el, true
+	}
+	return nil, false
+}
+
+// Float32 returns the current element if it is a single-precision
+// floating-point number.
+func (d *Decoder) Float32() (float32, bool) {
+	if d.Kind() != KindFloat {
+		return 0, false
+	}
+	return decodeFloat(d.Raw()), true
+}
+
+// Float64 returns the current element if it is a single- or double-precision
+// floating-point number.
+func (d *Decoder) Float64() (float64, bool) {
+	switch d.Kind() {
+	case KindFloat:
+		return float64(decodeFloat(d.Raw())), true
+	case KindDouble:
+		return decodeDouble(d.Raw()), true
+	}
+	return 0, false
+}
+
+// Bool returns the current element if it is a boolean.
+func (d *Decoder) Bool() (bool, bool) {
+	if d.Kind() != KindBool {
+		return false, false
+	}
+	return d.b[d.start] == 0x27, true
+}
+
+// UUID returns the current element if it is a UUID.
+func (d *Decoder) UUID() (UUID, bool) {
+	if d.Kind() != KindUUID {
+		return UUID{}, false
+	}
+	return decodeUUID(d.Raw()), true
+}
+
+// Versionstamp returns the current element if it is a versionstamp.
+func (d *Decoder) Versionstamp() (Versionstamp, bool) {
+	if d.Kind() != KindVersionstamp {
+		return Versionstamp{}, false
+	}
+	return decodeVersionstamp(d.Raw()), true
+}
+
+// Nested returns a Decoder reading the elements of the current element if it
+// is a nested tuple. The returned Decoder is independent of d, and is returned
+// by value so that decoding nested tuples does not allocate.
+func (d *Decoder) Nested() (Decoder, bool) {
+	if d.Kind() != KindTuple {
+		return Decoder{}, false
+	}
+	return Decoder{b: d.b[:d.pos], pos: d.start + 1, nested: true}, true
+}
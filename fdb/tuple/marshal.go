@@ -0,0 +1,396 @@
+// FoundationDB Go Tuple Layer
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A field is an exported struct field encoded as an element of a tuple.
+type field struct {
+	name string
+	index int
+	pos int
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	bigIntType = reflect.TypeOf(big.Int{})
+	tupleType = reflect.TypeOf(Tuple{})
+	uuidType = reflect.TypeOf(UUID{})
+	versionstampType = reflect.TypeOf(Versionstamp{})
+)
+
+// fieldsOf returns the fields of a struct type that are encoded as tuple
+// elements, in tuple order.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field), nil
+	}
+
+	var fields []field
+	var positioned int
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		tag := sf.Tag.Get("fdb")
+		if tag == "-" {
+			continue
+		}
+
+		f := field{name: sf.Name, index: i, pos: len(fields)}
+		if tag != "" {
+			pos, e := strconv.Atoi(tag)
+			if e != nil || pos < 0 {
+				return nil, fmt.Errorf("invalid fdb tag %q on field %s of %v (expected a tuple position or \"-\")", tag, sf.Name, t)
+			}
+			f.pos = pos
+			positioned++
+		}
+		fields = append(fields, f)
+	}
+
+	if positioned > 0 {
+		if positioned != len(fields) {
+			return nil, fmt.Errorf("either all or none of the encoded fields of %v must have an fdb tag giving their position", t)
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].pos < fields[j].pos })
+		for i, f := range fields {
+			if f.pos != i {
+				return nil, fmt.Errorf("the fdb tags of %v must number the positions of its fields from 0 without gaps or repetition", t)
+			}
+		}
+	}
+
+	fieldCache.Store(t, fields)
+
+	return fields, nil
+}
+
+// Marshal returns the packed tuple encoding of v, which must be a struct or a
+// pointer to a struct. Each exported field of the struct is encoded as one
+// element of the tuple, in the order the fields are declared. The encoding of
+// each field may be controlled by its fdb struct tag:
+//
+//	// Field is not encoded.
+//	Field int `fdb:"-"`
+//
+//	// Field is encoded as the third element of the tuple.
+//	Field int `fdb:"2"`
+//
+// If any field is given a position, every encoded field must be, and the
+// positions must number the fields from 0.
+//
+// Fields of the element types supported by Pack are encoded as themselves.
+// Other fields are converted to a supported element type: signed integers of
+// any size and unsigned integers other than uint64 are encoded as int64, a
+// time.Time is encoded as an int64 holding its Unix time in nanoseconds (and so
+// must lie between the years 1678 and 2262), a pointer is encoded as nil if it
+// is nil and as the value it points to otherwise, and a struct is encoded as a
+// nested tuple, as if marshalled by Marshal. Marshal returns an error if a
+// field is of any other type, or if the resulting tuple cannot be packed.
+//
+// Structs whose tuple encodings are used as keys sort first by the first
+// encoded field, then by the second, and so on.
+func Marshal(v interface{}) ([]byte, error) {
+	t, e := structToTuple(reflect.ValueOf(v))
+	if e != nil {
+		return nil, e
+	}
+	return t.PackWithError()
+}
+
+// Unmarshal decodes the packed tuple b into v, which must be a non-nil pointer
+// to a struct. The tuple must have exactly one element for each field encoded
+// by Marshal, and each element must be convertible to the type of the
+// corresponding field without loss (for example, Unmarshal returns an error
+// rather than truncating an integer too large for an int32 field). A time.Time
+// field is decoded in UTC.
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal tuple into %T (expected a non-nil pointer to a struct)", v)
+	}
+
+	t, e := Unpack(b)
+	if e != nil {
+		return e
+	}
+
+	return tupleToStruct(t, rv.Elem())
+}
+
+func structToTuple(v reflect.Value) (Tuple, error) {
+	if !v.IsValid() {
+		return nil, errors.New("cannot marshal nil as a tuple (expected a struct or a pointer to a struct)")
+	}
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot marshal %v as a tuple (expected a struct or a pointer to a struct)", v.Type())
+	}
+
+	fields, e := fieldsOf(v.Type())
+	if e != nil {
+		return nil, e
+	}
+
+	t := make(Tuple, len(fields))
+	for i, f := range fields {
+		el, e := toElement(v.Field(f.index))
+		if e != nil {
+			return nil, fmt.Errorf("cannot marshal field %s of %v: %v", f.name, v.Type(), e)
+		}
+		t[i] = el
+	}
+
+	return t, nil
+}
+
+func tupleToStruct(t Tuple, v reflect.Value) error {
+	fields, e := fieldsOf(v.Type())
+	if e != nil {
+		return e
+	}
+
+	if len(t) != len(fields) {
+		return fmt.Errorf("cannot unmarshal tuple of %d elements into %v (expected %d elements)", len(t), v.Type(), len(fields))
+	}
+
+	for i, f := range fields {
+		if e := fromElement(t[i], v.Field(f.index)); e != nil {
+			return fmt.Errorf("cannot unmarshal element %d into field %s of %v: %v", i, f.name, v.Type(), e)
+		}
+	}
+
+	return nil
+}
+
+// minTime and maxTime bound the times representable as an int64 of Unix
+// nanoseconds.
+var (
+	minTime = time.Unix(0, math.MinInt64)
+	maxTime = time.Unix(0, math.MaxInt64)
+)
+
+// toElement converts a field value to the tuple element encoding it.
+func toElement(v reflect.Value) (TupleElement, error) {
+	switch v.Type() {
+	case timeType:
+		tm := v.Interface().(time.Time)
+		if tm.Before(minTime) || tm.After(maxTime) {
+			return nil, fmt.Errorf("time %v cannot be represented in Unix nanoseconds", tm)
+		}
+		return tm.UnixNano(), nil
+	case bigIntType:
+		b := v.Interface().(big.Int)
+		return &b, nil
+	case tupleType, uuidType, versionstampType:
+		return v.Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Float32:
+		return float32(v.Float()), nil
+	case reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return u, nil
+		}
+		return int64(u), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem() == bigIntType {
+			return v.Interface(), nil
+		}
+		return toElement(v.Elem())
+	case reflect.Interface:
+		if v.Type().NumMethod() == 0 {
+			return v.Interface(), nil
+		}
+	case reflect.Struct:
+		return structToTuple(v)
+	}
+
+	return nil, fmt.Errorf("unsupported type %v", v.Type())
+}
+
+// fromElement sets a field value from the tuple element encoding it.
+func fromElement(el TupleElement, v reflect.Value) error {
+	switch v.Type() {
+	case timeType:
+		n, ok := el.(int64)
+		if !ok {
+			return fmt.Errorf("cannot decode %T as time.Time", el)
+		}
+		v.Set(reflect.ValueOf(time.Unix(0, n).UTC()))
+		return nil
+	case bigIntType:
+		i, e := toBigInt(el)
+		if e != nil {
+			return e
+		}
+		v.Set(reflect.ValueOf(*i))
+		return nil
+	case tupleType, uuidType, versionstampType:
+		ev := reflect.ValueOf(el)
+		if !ev.IsValid() || ev.Type() != v.Type() {
+			return fmt.Errorf("cannot decode %T as %v", el, v.Type())
+		}
+		v.Set(ev)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := el.(bool)
+		if !ok {
+			return fmt.Errorf("cannot decode %T as %v", el, v.Type())
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := el.(string)
+		if !ok {
+			return fmt.Errorf("cannot decode %T as %v", el, v.Type())
+		}
+		v.SetString(s)
+	case reflect.Float32, reflect.Float64:
+		switch f := el.(type) {
+		case float32:
+			v.SetFloat(float64(f))
+		case float64:
+			if v.Kind() == reflect.Float32 && float64(float32(f)) != f && !math.IsNaN(f) {
+				return fmt.Errorf("%v overflows or loses precision as %v", f, v.Type())
+			}
+			v.SetFloat(f)
+		default:
+			return fmt.Errorf("cannot decode %T as %v", el, v.Type())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := el.(int64)
+		if !ok || v.OverflowInt(n) {
+			return fmt.Errorf("cannot decode %v (%T) as %v", el, el, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch n := el.(type) {
+		case int64:
+			if n < 0 {
+				return fmt.Errorf("cannot decode %v as %v", n, v.Type())
+			}
+			u = uint64(n)
+		case uint64:
+			u = n
+		default:
+			return fmt.Errorf("cannot decode %v (%T) as %v", el, el, v.Type())
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("%v overflows %v", u, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Slice:
+		b, ok := el.([]byte)
+		if !ok || v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("cannot decode %T as %v", el, v.Type())
+		}
+		v.SetBytes(b)
+	case reflect.Ptr:
+		if el == nil {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.Type().Elem() == bigIntType {
+			i, e := toBigInt(el)
+			if e != nil {
+				return e
+			}
+			v.Set(reflect.ValueOf(i))
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if e := fromElement(el, p.Elem()); e != nil {
+			return e
+		}
+		v.Set(p)
+	case reflect.Interface:
+		if v.Type().NumMethod() != 0 {
+			return fmt.Errorf("unsupported type %v", v.Type())
+		}
+		if el == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(el))
+		}
+	case reflect.Struct:
+		t, ok := el.(Tuple)
+		if !ok {
+			return fmt.Errorf("cannot decode %T as %v", el, v.Type())
+		}
+		return tupleToStruct(t, v)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+
+	return nil
+}
+
+func toBigInt(el TupleElement) (*big.Int, error) {
+	switch n := el.(type) {
+	case int64:
+		return big.NewInt(n), nil
+	case uint64:
+		return new(big.Int).SetUint64(n), nil
+	case *big.Int:
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot decode %T as an integer", el)
+}
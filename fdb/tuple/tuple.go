@@ -28,25 +28,38 @@
 // For general guidance on tuple usage, see the Tuple section of Data Modeling
 // (https://foundationdb.com/documentation/data-modeling.html#data-modeling-tuples).
 //
-// FoundationDB tuples can currently encode byte and unicode strings, integers
-// and NULL values. In Go these are represented as []byte, string, int64 and
-// nil.
+// FoundationDB tuples can currently encode byte and unicode strings, integers,
+// single- and double-precision floating-point numbers, booleans, UUIDs,
+// versionstamps, nested tuples and NULL values. In Go these are represented as
+// []byte, string, int64 (or, for integers outside its range, uint64 or
+// *big.Int), float32, float64, bool, UUID, Versionstamp, Tuple and nil.
+//
+// Tuples may also be built from (and decoded into) Go structs with Marshal and
+// Unmarshal, which map struct fields to tuple elements as directed by fdb
+// struct tags.
 package tuple
 
 import (
 	"fmt"
+	"errors"
 	"encoding/binary"
 	"bytes"
+	"strings"
+	"math"
+	"math/big"
 	"github.com/FoundationDB/fdb-go/fdb"
 )
 
 // A TupleElement is one of the types that may be encoded in FoundationDB
 // tuples. Although the Go compiler cannot enforce this, it is a programming
 // error to use an unsupported types as a TupleElement (and will typically
-// result in a runtime panic).
+// result in a runtime panic). Tuples whose elements may not be valid (such as
+// those built from user input) may be checked with Validate, or packed with
+// PackWithError.
 //
 // The valid types for TupleElement are []byte (or fdb.KeyConvertible), string,
-// int64 (or int), and nil.
+// int64 (or int), uint64, *big.Int (or big.Int), float32, float64, bool, UUID,
+// Versionstamp, Tuple, and nil.
 type TupleElement interface{}
 
 // Tuple is a slice of objects that can be encoded as FoundationDB tuples. If
@@ -55,9 +68,111 @@ type TupleElement interface{}
 //
 // Given a Tuple T containing objects only of these types, then T will be
 // identical to the Tuple returned by unpacking the byte slice obtained by
-// packing T (modulo type normalization to []byte and int64).
+// packing T (modulo type normalization to []byte, and of integers to int64,
+// uint64 or *big.Int: an integer unpacks as an int64 if it is within the range
+// of int64, otherwise as a uint64 if it is within the range of uint64, and
+// otherwise as a *big.Int).
 type Tuple []TupleElement
 
+// ErrUnsupportedType is the error returned by PackWithError, Validate and
+// PackWithVersionstamp (and with which Pack panics) when a tuple contains an
+// element of a type that cannot be encoded.
+type ErrUnsupportedType struct {
+	// Index is the index of the unsupported element within the tuple or, if
+	// the element is within a nested tuple, of the outermost tuple containing
+	// it.
+	Index int
+
+	// Element is the unsupported element.
+	Element TupleElement
+}
+
+func (e ErrUnsupportedType) Error() string {
+	return fmt.Sprintf("unencodable element at index %d (%v, type %T)", e.Index, e.Element, e.Element)
+}
+
+// errIntegerTooLarge is the error returned when a tuple contains an integer
+// whose magnitude exceeds 255 bytes.
+type errIntegerTooLarge struct {
+	// index is as the Index of ErrUnsupportedType
+	index int
+	size int
+}
+
+func (e errIntegerTooLarge) Error() string {
+	return fmt.Sprintf("integer at index %d is too large to encode (magnitude of %d bytes, at most 255 allowed)", e.index, e.size)
+}
+
+// atIndex returns an error encoding a tuple, reported as occurring at the
+// element with index i.
+func atIndex(err error, i int) error {
+	switch e := err.(type) {
+	case ErrUnsupportedType:
+		e.Index = i
+		return e
+	case errIntegerTooLarge:
+		e.index = i
+		return e
+	}
+	return err
+}
+
+var errIncompleteVersionstamp = errors.New("tuple contains an incomplete versionstamp (use PackWithVersionstamp)")
+
+// UUID is a tuple element holding a 16-byte universally unique identifier
+// (RFC 4122), encoded in its standard (big-endian) byte order.
+type UUID [16]byte
+
+// String returns the standard hyphenated hexadecimal representation of the
+// UUID.
+func (u UUID) String() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Versionstamp is a tuple element holding a versionstamp: the 10-byte
+// versionstamp assigned to a transaction by FoundationDB when it commits (see
+// (fdb.Transaction).GetVersionstamp), followed by a 2-byte user version
+// chosen by the client to order multiple versionstamps written by the same
+// transaction.
+//
+// A Versionstamp whose transaction version is not yet known is incomplete. A
+// tuple containing an incomplete versionstamp must be packed with
+// PackWithVersionstamp and written with (fdb.Transaction).SetVersionstampedKey
+// or SetVersionstampedValue, which fill in the transaction version when the
+// transaction commits.
+type Versionstamp struct {
+	TransactionVersion [10]byte
+	UserVersion uint16
+}
+
+var incompleteTransactionVersion = [10]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+// IncompleteVersionstamp returns an incomplete Versionstamp with the provided
+// user version.
+func IncompleteVersionstamp(userVersion uint16) Versionstamp {
+	return Versionstamp{incompleteTransactionVersion, userVersion}
+}
+
+// IsComplete returns true if the transaction version of the Versionstamp is
+// known.
+func (v Versionstamp) IsComplete() bool {
+	return v.TransactionVersion != incompleteTransactionVersion
+}
+
+// Bytes returns the 12-byte representation of the Versionstamp: the
+// transaction version followed by the big-endian user version.
+func (v Versionstamp) Bytes() []byte {
+	b := make([]byte, 12)
+	copy(b, v.TransactionVersion[:])
+	binary.BigEndian.PutUint16(b[10:], v.UserVersion)
+	return b
+}
+
+// String returns a hexadecimal representation of the Versionstamp.
+func (v Versionstamp) String() string {
+	return fmt.Sprintf("Versionstamp(%x, %d)", v.TransactionVersion[:], v.UserVersion)
+}
+
 var sizeLimits = []uint64{
 	1 << (0 * 8) - 1,
 	1 << (1 * 8) - 1,
@@ -70,10 +185,66 @@ var sizeLimits = []uint64{
 	1 << (8 * 8) - 1,
 }
 
-func encodeBytes(buf *bytes.Buffer, code byte, b []byte) {
-	buf.WriteByte(code)
-	buf.Write(bytes.Replace(b, []byte{0x00}, []byte{0x00, 0xFF}, -1))
-	buf.WriteByte(0x00)
+// appendBytes appends the encoding of a byte string with the provided typecode,
+// escaping each 0x00 byte as 0x00 0xFF and terminating the string with 0x00.
+func appendBytes(dst []byte, code byte, b []byte) []byte {
+	dst = append(dst, code)
+	for {
+		idx := bytes.IndexByte(b, 0x00)
+		if idx < 0 {
+			break
+		}
+		dst = append(append(dst, b[:idx+1]...), 0xFF)
+		b = b[idx+1:]
+	}
+	return append(append(dst, b...), 0x00)
+}
+
+// appendString is like appendBytes, but encodes a string without first
+// converting it to a byte slice.
+func appendString(dst []byte, code byte, s string) []byte {
+	dst = append(dst, code)
+	for {
+		idx := strings.IndexByte(s, 0x00)
+		if idx < 0 {
+			break
+		}
+		dst = append(append(dst, s[:idx+1]...), 0xFF)
+		s = s[idx+1:]
+	}
+	return append(append(dst, s...), 0x00)
+}
+
+// appendBigEndian appends the n least significant bytes of u, most significant
+// first.
+func appendBigEndian(dst []byte, u uint64, n int) []byte {
+	for shift := (n - 1) * 8; shift >= 0; shift -= 8 {
+		dst = append(dst, byte(u >> uint(shift)))
+	}
+	return dst
+}
+
+// readBigEndian returns the big-endian unsigned integer encoded by up to 8
+// bytes.
+func readBigEndian(b []byte) uint64 {
+	var u uint64
+	for _, c := range b {
+		u = u << 8 | uint64(c)
+	}
+	return u
+}
+
+// appendFloat appends the encoding of a floating-point number with the provided
+// bits. The sign bit of positive numbers is flipped, and all bits of negative
+// numbers are flipped, so that the encodings sort in numerical order.
+func appendFloat(dst []byte, code byte, bits uint64, size int) []byte {
+	if bits & (1 << uint(size * 8 - 1)) != 0 {
+		bits = ^bits
+	} else {
+		bits ^= 1 << uint(size * 8 - 1)
+	}
+
+	return appendBigEndian(append(dst, code), bits, size)
 }
 
 func bisectLeft(u uint64) int {
@@ -84,67 +255,271 @@ func bisectLeft(u uint64) int {
 	return n
 }
 
-func encodeInt(buf *bytes.Buffer, i int64) {
-	if i == 0 {
-		buf.WriteByte(0x14)
-		return
+func appendInt(dst []byte, i int64) []byte {
+	switch {
+	case i > 0:
+		n := bisectLeft(uint64(i))
+		return appendBigEndian(append(dst, byte(0x14+n)), uint64(i), n)
+	case i < 0:
+		n := bisectLeft(uint64(-i))
+		return appendBigEndian(append(dst, byte(0x14-n)), sizeLimits[n]+uint64(i), n)
 	}
+	return append(dst, 0x14)
+}
 
-	var n int
-	var ibuf bytes.Buffer
+func appendUint(dst []byte, u uint64) []byte {
+	if u <= math.MaxInt64 {
+		return appendInt(dst, int64(u))
+	}
+	return appendBigEndian(append(dst, 0x1c), u, 8)
+}
+
+// onesLimit returns the largest integer encodable in n bytes, 2^(8n) - 1.
+func onesLimit(n int) *big.Int {
+	l := new(big.Int).Lsh(big.NewInt(1), uint(n * 8))
+	return l.Sub(l, big.NewInt(1))
+}
+
+// appendBigInt appends the encoding of an arbitrary-precision integer. Integers
+// with magnitudes of more than 8 bytes are encoded with the typecode 0x1d
+// (positive) or 0x0b (negative), followed by the length of the magnitude and
+// the magnitude itself (or, for negative integers, its ones' complement).
+func appendBigInt(dst []byte, i *big.Int) ([]byte, error) {
+	if i.IsInt64() {
+		return appendInt(dst, i.Int64()), nil
+	}
+	if i.IsUint64() {
+		return appendUint(dst, i.Uint64()), nil
+	}
+
+	n := (i.BitLen() + 7) / 8
+	if n > 255 {
+		return nil, errIntegerTooLarge{size: n}
+	}
 
 	switch {
-	case i > 0:
-		n = bisectLeft(uint64(i))
-		buf.WriteByte(byte(0x14+n))
-		binary.Write(&ibuf, binary.BigEndian, i)
-	case i < 0:
-		n = bisectLeft(uint64(-i))
-		buf.WriteByte(byte(0x14-n))
-		binary.Write(&ibuf, binary.BigEndian, int64(sizeLimits[n])+i)
+	case n <= 8:
+		dst = append(dst, byte(0x14-n))
+	case i.Sign() > 0:
+		dst = append(dst, 0x1d, byte(n))
+	default:
+		dst = append(dst, 0x0b, byte(n) ^ 0xFF)
 	}
 
-	buf.Write(ibuf.Bytes()[8-n:])
+	b := make([]byte, n)
+	if i.Sign() > 0 {
+		i.FillBytes(b)
+	} else {
+		new(big.Int).Add(i, onesLimit(n)).FillBytes(b)
+	}
+
+	return append(dst, b...), nil
 }
 
-// Pack returns a new byte slice encoding the provided tuple. Pack will panic if
-// the tuple contains an element of any type other than []byte,
-// fdb.KeyConvertible, string, int64, int or nil.
-//
-// Tuple satisfies the fdb.KeyConvertible interface, so it is not necessary to
-// call Pack when using a Tuple with a FoundationDB API function that requires a
-// key.
-func (t Tuple) Pack() []byte {
-	buf := new(bytes.Buffer)
+// encode appends the encoding of the tuple to dst, and the positions within dst
+// of the transaction versions of any incomplete versionstamps to stamps, or
+// returns an error if an element cannot be encoded. Nil elements of a nested
+// tuple are escaped to distinguish them from the end of the nested tuple.
+func (t Tuple) encode(dst []byte, nested bool, stamps []int) ([]byte, []int, error) {
+	var err error
 
 	for i, e := range(t) {
 		switch e := e.(type) {
 		case nil:
-			buf.WriteByte(0x00)
+			dst = append(dst, 0x00)
+			if nested {
+				dst = append(dst, 0xFF)
+			}
+		case Tuple:
+			dst, stamps, err = e.encode(append(dst, 0x05), true, stamps)
+			if err != nil {
+				return nil, nil, atIndex(err, i)
+			}
+			dst = append(dst, 0x00)
 		case int64:
-			encodeInt(buf, e)
+			dst = appendInt(dst, e)
 		case int:
-			encodeInt(buf, int64(e))
+			dst = appendInt(dst, int64(e))
+		case uint64:
+			dst = appendUint(dst, e)
+		case *big.Int:
+			if dst, err = appendBigInt(dst, e); err != nil {
+				return nil, nil, atIndex(err, i)
+			}
+		case big.Int:
+			if dst, err = appendBigInt(dst, &e); err != nil {
+				return nil, nil, atIndex(err, i)
+			}
 		case []byte:
-			encodeBytes(buf, 0x01, e)
+			dst = appendBytes(dst, 0x01, e)
 		case fdb.KeyConvertible:
-			encodeBytes(buf, 0x01, []byte(e.FDBKey()))
+			dst = appendBytes(dst, 0x01, e.FDBKey())
 		case string:
-			encodeBytes(buf, 0x02, []byte(e))
+			dst = appendString(dst, 0x02, e)
+		case float32:
+			dst = appendFloat(dst, 0x20, uint64(math.Float32bits(e)), 4)
+		case float64:
+			dst = appendFloat(dst, 0x21, math.Float64bits(e), 8)
+		case bool:
+			if e {
+				dst = append(dst, 0x27)
+			} else {
+				dst = append(dst, 0x26)
+			}
+		case UUID:
+			dst = append(append(dst, 0x30), e[:]...)
+		case Versionstamp:
+			dst = append(dst, 0x33)
+			if !e.IsComplete() {
+				stamps = append(stamps, len(dst))
+			}
+			dst = append(dst, e.TransactionVersion[:]...)
+			dst = appendBigEndian(dst, uint64(e.UserVersion), 2)
 		default:
-			panic(fmt.Sprintf("unencodable element at index %d (%v, type %T)", i, t[i], t[i]))
+			return nil, nil, ErrUnsupportedType{i, t[i]}
+		}
+	}
+
+	return dst, stamps, nil
+}
+
+// Pack returns a new byte slice encoding the provided tuple. Pack will panic if
+// the tuple (or any tuple nested within it) contains an element of any type
+// other than []byte, fdb.KeyConvertible, string, int64, int, uint64, *big.Int,
+// big.Int, float32, float64, bool, UUID, Versionstamp, Tuple or nil, if it
+// contains an integer whose magnitude exceeds 255 bytes, or if it contains an
+// incomplete Versionstamp (use PackWithVersionstamp instead).
+//
+// Tuple satisfies the fdb.KeyConvertible interface, so it is not necessary to
+// call Pack when using a Tuple with a FoundationDB API function that requires a
+// key.
+func (t Tuple) Pack() []byte {
+	b, e := t.PackWithError()
+	if e != nil {
+		panic(e)
+	}
+	return b
+}
+
+// PackWithError returns a new byte slice encoding the provided tuple, or an
+// error in the circumstances in which Pack would panic. If the tuple contains
+// an element of an unsupported type, the error is an ErrUnsupportedType.
+func (t Tuple) PackWithError() ([]byte, error) {
+	return t.appendPack(make([]byte, 0, 64))
+}
+
+// AppendPack appends the encoding of the provided tuple to dst and returns the
+// extended slice, panicking in the same circumstances as Pack. Encoding a tuple
+// of elements other than big integers does not allocate if dst has sufficient
+// capacity, so (unlike Pack) AppendPack may be used to build many keys in a
+// reused buffer without allocating:
+//
+//	buf = tuple.AppendPack(buf[:0], t)
+func AppendPack(dst []byte, t Tuple) []byte {
+	b, e := t.appendPack(dst)
+	if e != nil {
+		panic(e)
+	}
+	return b
+}
+
+func (t Tuple) appendPack(dst []byte) ([]byte, error) {
+	b, stamps, e := t.encode(dst, false, nil)
+	if e != nil {
+		return nil, e
+	}
+	if len(stamps) > 0 {
+		return nil, errIncompleteVersionstamp
+	}
+	return b, nil
+}
+
+// Validate returns an error if the provided tuple cannot be encoded: that is,
+// if it (or any tuple nested within it) contains an element of an unsupported
+// type (in which case the error is an ErrUnsupportedType), or an integer whose
+// magnitude exceeds 255 bytes. A tuple containing incomplete versionstamps is
+// valid, although it may only be packed with PackWithVersionstamp.
+func Validate(t Tuple) error {
+	_, _, e := t.encode(nil, false, nil)
+	return e
+}
+
+// HasIncompleteVersionstamp returns true if the tuple (or any tuple nested
+// within it) contains an incomplete Versionstamp.
+func (t Tuple) HasIncompleteVersionstamp() bool {
+	for _, e := range t {
+		switch e := e.(type) {
+		case Versionstamp:
+			if !e.IsComplete() {
+				return true
+			}
+		case Tuple:
+			if e.HasIncompleteVersionstamp() {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	return buf.Bytes()
+// getAPIVersion returns the selected API version, which determines the size of
+// the versionstamp offset. It is replaced in tests.
+var getAPIVersion = fdb.GetAPIVersion
+
+// PackWithVersionstamp returns a new byte slice encoding the provided prefix
+// followed by the tuple, which must contain exactly one incomplete
+// Versionstamp, and ending with the offset of the versionstamp within the
+// encoding. The result is suitable as the key passed to
+// (fdb.Transaction).SetVersionstampedKey, or (from API version 520) the
+// parameter passed to SetVersionstampedValue; when the transaction commits, the
+// offset is removed and the versionstamp of the transaction is written in place
+// of the incomplete transaction version.
+//
+// The offset is 4 bytes, little-endian, unless an API version before 520 has
+// been selected, in which case it is 2 bytes. PackWithVersionstamp returns an
+// error if the tuple does not contain exactly one incomplete Versionstamp, or
+// if the offset cannot be represented, and in the same circumstances as
+// PackWithError for elements that cannot be encoded.
+func (t Tuple) PackWithVersionstamp(prefix []byte) ([]byte, error) {
+	b, stamps, e := t.encode(append([]byte{}, prefix...), false, nil)
+	if e != nil {
+		return nil, e
+	}
+	switch len(stamps) {
+	case 0:
+		return nil, errors.New("tuple does not contain an incomplete versionstamp")
+	case 1:
+	default:
+		return nil, fmt.Errorf("tuple contains %d incomplete versionstamps (only one is allowed)", len(stamps))
+	}
+
+	if v, e := getAPIVersion(); e == nil && v < 520 {
+		if stamps[0] > math.MaxUint16 {
+			return nil, fmt.Errorf("versionstamp offset %d is too large for API version %d", stamps[0], v)
+		}
+		b = append(b, byte(stamps[0]), byte(stamps[0] >> 8))
+	} else {
+		if int64(stamps[0]) > math.MaxUint32 {
+			return nil, fmt.Errorf("versionstamp offset %d is too large", stamps[0])
+		}
+		b = append(b, byte(stamps[0]), byte(stamps[0] >> 8), byte(stamps[0] >> 16), byte(stamps[0] >> 24))
+	}
+
+	return b, nil
 }
 
-func findTerminator(b []byte) int {
+// findTerminator returns the length of the escaped byte string at the start
+// of b, which ends with an unescaped 0x00 byte, or false if b contains no
+// terminator.
+func findTerminator(b []byte) (int, bool) {
 	bp := b
 	var length int
 
 	for {
 		idx := bytes.IndexByte(bp, 0x00)
+		if idx < 0 {
+			return 0, false
+		}
 		length += idx
 		if idx + 1 == len(bp) || bp[idx+1] != 0xFF {
 			break
@@ -153,76 +528,272 @@ func findTerminator(b []byte) int {
 		bp = bp[idx+2:]
 	}
 
-	return length
+	return length, true
 }
 
-func decodeBytes(b []byte) ([]byte, int) {
-	idx := findTerminator(b[1:])
-	return bytes.Replace(b[1:idx+1], []byte{0x00, 0xFF}, []byte{0x00}, -1), idx + 2
+// unescape returns the byte string encoded by the escaped bytes b (excluding
+// the typecode and terminator). If b contains no escaped 0x00 bytes and alias
+// is true, unescape returns b itself rather than a copy.
+func unescape(b []byte, alias bool) []byte {
+	if alias && bytes.IndexByte(b, 0x00) < 0 {
+		return b
+	}
+	return bytes.Replace(b, []byte{0x00, 0xFF}, []byte{0x00}, -1)
 }
 
-func decodeString(b []byte) (string, int) {
-	bp, idx := decodeBytes(b)
-	return string(bp), idx
+func decodeFloat(b []byte) float32 {
+	bits := uint32(readBigEndian(b[1:5]))
+	if bits & (1 << 31) != 0 {
+		bits ^= 1 << 31
+	} else {
+		bits = ^bits
+	}
+	return math.Float32frombits(bits)
 }
 
-func decodeInt(b []byte) (int64, int) {
-	if b[0] == 0x14 {
-		return 0, 1
+func decodeDouble(b []byte) float64 {
+	bits := readBigEndian(b[1:9])
+	if bits & (1 << 63) != 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
 	}
+	return math.Float64frombits(bits)
+}
 
-	var neg bool
+func decodeUUID(b []byte) UUID {
+	var u UUID
+	copy(u[:], b[1:17])
+	return u
+}
+
+func decodeVersionstamp(b []byte) Versionstamp {
+	var v Versionstamp
+	copy(v.TransactionVersion[:], b[1:11])
+	v.UserVersion = uint16(readBigEndian(b[11:13]))
+	return v
+}
 
-	n := int(b[0]) - 20
-	if n < 0 {
-		n = -n
-		neg = true
+func decodeBigInt(b []byte, neg bool) *big.Int {
+	ret := new(big.Int).SetBytes(b)
+	if neg {
+		ret.Sub(ret, onesLimit(len(b)))
 	}
+	return ret
+}
 
-	bp := make([]byte, 8)
-	copy(bp[8-n:], b[1:n+1])
+// decodeSmallInt decodes an integer with a magnitude of up to 8 bytes,
+// returning its value as an int64 (if it is within the range of int64) or as
+// a uint64 (if it is positive), or false if it is a negative integer beyond
+// the range of int64.
+func decodeSmallInt(b []byte) (int64, uint64, bool) {
+	n := intLength(b[0])
+	u := readBigEndian(b[1:n+1])
 
-	var ret int64
+	switch {
+	case b[0] >= 0x14 && u > math.MaxInt64:
+		return 0, u, true
+	case b[0] >= 0x14:
+		return int64(u), 0, true
+	case n == 8 && u < math.MaxInt64:
+		return 0, 0, false
+	}
 
-	binary.Read(bytes.NewBuffer(bp), binary.BigEndian, &ret)
+	return int64(u - sizeLimits[n]), 0, true
+}
 
-	if neg {
-		ret -= int64(sizeLimits[n])
+// decodeInt decodes an integer with a magnitude of up to 8 bytes, returning it
+// as an int64 if it is within the range of int64, and otherwise as a uint64
+// (if positive) or a *big.Int.
+func decodeInt(b []byte) TupleElement {
+	i, u, ok := decodeSmallInt(b)
+	switch {
+	case !ok:
+		return decodeBigInt(b[1:intLength(b[0])+1], true)
+	case u != 0:
+		return u
 	}
+	return i
+}
 
-	return ret, n+1
+// decodeLargeInt decodes an integer with a magnitude of more than 8 bytes,
+// encoded with the typecode 0x1d or 0x0b.
+func decodeLargeInt(b []byte) *big.Int {
+	return decodeBigInt(b[2:], b[0] == 0x0b)
 }
 
-// Unpack returns the tuple encoded by the provided byte slice, or an error if
-// the key does not correctly encode a FoundationDB tuple.
-func Unpack(b []byte) (Tuple, error) {
-	var t Tuple
+// lengthMask returns the mask applied to the length of an integer encoded with
+// the typecode 0x0b or 0x1d.
+func lengthMask(code byte) byte {
+	if code == 0x0b {
+		return 0xFF
+	}
+	return 0x00
+}
 
-	var i int
+// fixedSize returns the encoded size (including the typecode) of an element
+// with the provided typecode, or 0 if its size is not fixed.
+func fixedSize(code byte) int {
+	switch code {
+	case 0x00, 0x26, 0x27:
+		return 1
+	case 0x20:
+		return 5
+	case 0x21:
+		return 9
+	case 0x30:
+		return 17
+	case 0x33:
+		return 13
+	}
+	if 0x0c <= code && code <= 0x1c {
+		return 1 + intLength(code)
+	}
+	return 0
+}
+
+// intLength returns the length of the magnitude of an integer encoded with a
+// typecode between 0x0c and 0x1c.
+func intLength(code byte) int {
+	if code < 0x14 {
+		return int(0x14 - code)
+	}
+	return int(code - 0x14)
+}
+
+// isTerminator returns true if position i of b holds the 0x00 byte ending a
+// nested tuple (rather than the first byte of an escaped nil element).
+func isTerminator(b []byte, i int) bool {
+	return b[i] == 0x00 && (i + 1 == len(b) || b[i+1] != 0xFF)
+}
+
+// elementEnd returns the position following the element encoded at position i
+// of b, which must lie within a nested tuple if nested is true. It returns an
+// error if the element is of an unknown type or is not entirely within b, so
+// an element that elementEnd accepts may be decoded without bounds checks.
+func elementEnd(b []byte, i int, nested bool) (int, error) {
+	code := b[i]
+
+	switch {
+	case code == 0x00 && nested:
+		return i + 2, nil
+	case code == 0x01 || code == 0x02:
+		n, ok := findTerminator(b[i+1:])
+		if !ok {
+			return 0, fmt.Errorf("unterminated string with typecode %02x at position %d", code, i)
+		}
+		return i + n + 2, nil
+	case code == 0x05:
+		j := i + 1
+		for j < len(b) {
+			if isTerminator(b, j) {
+				return j + 1, nil
+			}
+			var e error
+			if j, e = elementEnd(b, j, true); e != nil {
+				return 0, e
+			}
+		}
+		return 0, fmt.Errorf("unterminated nested tuple at end of input (position %d)", j)
+	case code == 0x0b || code == 0x1d:
+		if i + 2 > len(b) || i + 2 + int(b[i+1]^lengthMask(code)) > len(b) {
+			return 0, fmt.Errorf("insufficient bytes to decode integer with typecode %02x at position %d", code, i)
+		}
+		return i + 2 + int(b[i+1]^lengthMask(code)), nil
+	}
+
+	size := fixedSize(code)
+	if size == 0 {
+		return 0, fmt.Errorf("unable to decode tuple element with unknown typecode %02x at position %d", code, i)
+	}
+	if i + size > len(b) {
+		return 0, fmt.Errorf("insufficient bytes to decode tuple element with typecode %02x at position %d", code, i)
+	}
+	return i + size, nil
+}
+
+// decodeElement decodes the element encoded by b, which elementEnd must have
+// accepted. Byte strings are copied from b if alias is false.
+func decodeElement(b []byte, alias bool) TupleElement {
+	switch code := b[0]; {
+	case code == 0x00:
+		return nil
+	case code == 0x01:
+		return unescape(b[1:len(b)-1], alias)
+	case code == 0x02:
+		return string(unescape(b[1:len(b)-1], true))
+	case code == 0x05:
+		t, _, _ := decodeTuple(b, 1, true)
+		if t == nil {
+			t = Tuple{}
+		}
+		return t
+	case 0x0c <= code && code <= 0x1c:
+		return decodeInt(b)
+	case code == 0x0b || code == 0x1d:
+		return decodeLargeInt(b)
+	case code == 0x20:
+		return decodeFloat(b)
+	case code == 0x21:
+		return decodeDouble(b)
+	case code == 0x26:
+		return false
+	case code == 0x27:
+		return true
+	case code == 0x30:
+		return decodeUUID(b)
+	}
+	return decodeVersionstamp(b)
+}
+
+// decodeTuple decodes the tuple encoded by b starting at position i, returning
+// it and the position following it. A nested tuple ends at its (unescaped)
+// terminating 0x00 byte, and an unnested one at the end of b. Every element is
+// checked to lie within b before it is decoded, so decodeTuple returns an error
+// (rather than panicking) if b is truncated or otherwise malformed.
+func decodeTuple(b []byte, i int, nested bool) (Tuple, int, error) {
+	var t Tuple
 
 	for i < len(b) {
-		var el interface{}
-		var off int
-
-		switch {
-		case b[i] == 0x00:
-			el = nil
-			off = 1
-		case b[i] == 0x01:
-			el, off = decodeBytes(b[i:])
-		case b[i] == 0x02:
-			el, off = decodeString(b[i:])
-		case 0x0c <= b[i] && b[i] <= 0x1c:
-			el, off = decodeInt(b[i:])
-		default:
-			return nil, fmt.Errorf("unable to decode tuple element with unknown typecode %02x", b[i])
+		if nested && isTerminator(b, i) {
+			return t, i + 1, nil
+		}
+
+		if b[i] == 0x05 {
+			nt, end, e := decodeTuple(b, i + 1, true)
+			if e != nil {
+				return nil, i, e
+			}
+			if nt == nil {
+				nt = Tuple{}
+			}
+			t = append(t, nt)
+			i = end
+			continue
 		}
 
-		t = append(t, el)
-		i += off
+		end, e := elementEnd(b, i, nested)
+		if e != nil {
+			return nil, i, e
+		}
+
+		t = append(t, decodeElement(b[i:end], false))
+		i = end
+	}
+
+	if nested {
+		return nil, i, fmt.Errorf("unterminated nested tuple at end of input (position %d)", i)
 	}
 
-	return t, nil
+	return t, i, nil
+}
+
+// Unpack returns the tuple encoded by the provided byte slice, or an error if
+// the key does not correctly encode a FoundationDB tuple. Unpack does not panic
+// on malformed input, such as truncated or corrupt keys.
+func Unpack(b []byte) (Tuple, error) {
+	t, _, e := decodeTuple(b, 0, false)
+	return t, e
 }
 
 // FDBKey returns the packed representation of a Tuple, and allows Tuple to
@@ -250,6 +821,57 @@ func (t Tuple) FDBRangeKeySelectors() (fdb.Selectable, fdb.Selectable) {
 	return fdb.FirstGreaterOrEqual(b), fdb.FirstGreaterOrEqual(e)
 }
 
+// Range returns the range of keys that encode the Tuple itself and all tuples
+// starting with it. Unlike the range represented by the Tuple as an
+// fdb.ExactRange, the range includes the key encoding the Tuple. Range will
+// panic in the same circumstances as Pack.
+func (t Tuple) Range() fdb.KeyRange {
+	p := t.Pack()
+	return fdb.KeyRange{Begin: fdb.Key(p), End: fdb.Key(concat(p, 0xFF))}
+}
+
+// RangeBetween returns the range of keys that encode tuples starting with
+// prefix and followed by an element that is at least lo and less than hi (in
+// the order of encoded tuples), together with any further elements. That is,
+// the range begins with the key encoding prefix followed by lo, and ends
+// before the key encoding prefix followed by hi. RangeBetween will panic if
+// prefix, lo or hi cannot be packed.
+func RangeBetween(prefix Tuple, lo, hi TupleElement) fdb.KeyRange {
+	p := prefix.Pack()
+	return fdb.KeyRange{Begin: fdb.Key(AppendPack(concat(p), Tuple{lo})), End: fdb.Key(AppendPack(concat(p), Tuple{hi}))}
+}
+
+// Compare returns an integer comparing two tuples in the order of their
+// encodings: 0 if a and b encode to the same key, -1 if a sorts before b, and
+// +1 if a sorts after b. A tuple sorts before any longer tuple of which it is a
+// prefix. Compare does not pack either tuple as a whole, but will panic if it
+// needs to compare an element that cannot be encoded.
+func Compare(a, b Tuple) int {
+	var bufA, bufB [32]byte
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ea, _, e := a[i:i+1].encode(bufA[:0], false, nil)
+		if e != nil {
+			panic(e)
+		}
+		eb, _, e := b[i:i+1].encode(bufB[:0], false, nil)
+		if e != nil {
+			panic(e)
+		}
+		if c := bytes.Compare(ea, eb); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
 func concat(a []byte, b ...byte) []byte {
 	r := make([]byte, len(a) + len(b))
 	copy(r, a)
@@ -0,0 +1,634 @@
+// FoundationDB Go API
+// Copyright (c) 2013 FoundationDB, LLC
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package tuple_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FoundationDB/fdb-go/fdb"
+	"github.com/FoundationDB/fdb-go/fdb/tuple"
+)
+
+// validTuples are packed to seed the fuzz corpus with well-formed encodings of
+// every element type.
+var validTuples = []tuple.Tuple{
+	{},
+	{nil},
+	{[]byte("foo\x00bar"), "héllo\x00"},
+	{int64(0), 1, -1, math.MaxInt64, math.MinInt64, uint64(math.MaxUint64)},
+	{new(big.Int).Lsh(big.NewInt(1), 100), new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 100))},
+	{float32(-1.5), 3.25, math.Inf(-1), math.NaN()},
+	{true, false},
+	{tuple.UUID{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}},
+	{tuple.Versionstamp{[10]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 11}},
+	{tuple.Tuple{nil, tuple.Tuple{}, "a"}, tuple.Tuple{tuple.Tuple{nil}}},
+}
+
+// malformed are encodings that Unpack must reject with an error.
+var malformed = [][]byte{
+	{0x01, 'f', 'o', 'o'},
+	{0x02, 'f', 0x00, 0xFF},
+	{0x05, 0x15, 0x01},
+	{0x05, 0x00, 0xFF},
+	{0x15},
+	{0x1c, 0xFF, 0xFF},
+	{0x0c, 0x00},
+	{0x1d},
+	{0x1d, 0x09, 0x01},
+	{0x0b, 0xf6, 0xfe},
+	{0x20, 0x00},
+	{0x21, 0x00, 0x00, 0x00},
+	{0x30, 0x00},
+	{0x33, 0x00, 0x00},
+	{0x40},
+}
+
+func bigInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 0)
+	return i
+}
+
+// golden are tuples with their encodings as produced by the Python and Java
+// bindings.
+var golden = []struct {
+	t tuple.Tuple
+	b string
+}{
+	{tuple.Tuple{float32(1)}, "\x20\xbf\x80\x00\x00"},
+	{tuple.Tuple{float32(-1)}, "\x20\x40\x7f\xff\xff"},
+	{tuple.Tuple{float32(0)}, "\x20\x80\x00\x00\x00"},
+	{tuple.Tuple{float32(math.Copysign(0, -1))}, "\x20\x7f\xff\xff\xff"},
+	{tuple.Tuple{float32(math.Inf(1))}, "\x20\xff\x80\x00\x00"},
+	{tuple.Tuple{float32(math.Inf(-1))}, "\x20\x00\x7f\xff\xff"},
+	{tuple.Tuple{math.Float32frombits(0x7fc00000)}, "\x20\xff\xc0\x00\x00"},
+	{tuple.Tuple{3.14}, "\x21\xc0\x09\x1e\xb8\x51\xeb\x85\x1f"},
+	{tuple.Tuple{-3.14}, "\x21\x3f\xf6\xe1\x47\xae\x14\x7a\xe0"},
+	{tuple.Tuple{0.0}, "\x21\x80\x00\x00\x00\x00\x00\x00\x00"},
+	{tuple.Tuple{math.Copysign(0, -1)}, "\x21\x7f\xff\xff\xff\xff\xff\xff\xff"},
+	{tuple.Tuple{math.Inf(1)}, "\x21\xff\xf0\x00\x00\x00\x00\x00\x00"},
+	{tuple.Tuple{math.Inf(-1)}, "\x21\x00\x0f\xff\xff\xff\xff\xff\xff"},
+	{tuple.Tuple{math.Float64frombits(0x7ff8000000000000)}, "\x21\xff\xf8\x00\x00\x00\x00\x00\x00"},
+	{tuple.Tuple{true, false}, "\x27\x26"},
+	{tuple.Tuple{tuple.UUID{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}}, "\x30\x12\x34\x56\x78\x9a\xbc\xde\xf0\x12\x34\x56\x78\x9a\xbc\xde\xf0"},
+	{tuple.Tuple{tuple.Tuple{[]byte("foo\x00bar"), nil, tuple.Tuple{}}}, "\x05\x01foo\x00\xffbar\x00\x00\xff\x05\x00\x00"},
+	{tuple.Tuple{tuple.Tuple{nil}, nil}, "\x05\x00\xff\x00\x00"},
+	{tuple.Tuple{tuple.Tuple{tuple.Tuple{nil, "a"}}}, "\x05\x05\x00\xff\x02a\x00\x00\x00"},
+	{tuple.Tuple{uint64(math.MaxUint64)}, "\x1c\xff\xff\xff\xff\xff\xff\xff\xff"},
+	{tuple.Tuple{bigInt("-0xffffffffffffffff")}, "\x0c\x00\x00\x00\x00\x00\x00\x00\x00"},
+	{tuple.Tuple{bigInt("0x10000000000000000")}, "\x1d\x09\x01\x00\x00\x00\x00\x00\x00\x00\x00"},
+	{tuple.Tuple{bigInt("-0x10000000000000000")}, "\x0b\xf6\xfe\xff\xff\xff\xff\xff\xff\xff\xff"},
+	{tuple.Tuple{bigInt("0x123456789abcdef0123")}, "\x1d\x0a\x01\x23\x45\x67\x89\xab\xcd\xef\x01\x23"},
+	{tuple.Tuple{bigInt("-0x123456789abcdef0123")}, "\x0b\xf5\xfe\xdc\xba\x98\x76\x54\x32\x10\xfe\xdc"},
+	{tuple.Tuple{tuple.Versionstamp{[10]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 11}}, "\x33\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x00\x0b"},
+}
+
+// equal reports whether the unpacked element u is the element e, comparing
+// floats by their bits and integers by value.
+func equal(e, u interface{}) bool {
+	switch e := e.(type) {
+	case float32:
+		u, ok := u.(float32)
+		return ok && math.Float32bits(e) == math.Float32bits(u)
+	case float64:
+		u, ok := u.(float64)
+		return ok && math.Float64bits(e) == math.Float64bits(u)
+	case *big.Int:
+		u, ok := u.(*big.Int)
+		return ok && e.Cmp(u) == 0
+	case tuple.Tuple:
+		u, ok := u.(tuple.Tuple)
+		if !ok || len(e) != len(u) {
+			return false
+		}
+		for i := range e {
+			if !equal(e[i], u[i]) {
+				return false
+			}
+		}
+		return true
+	case []byte:
+		u, ok := u.([]byte)
+		return ok && bytes.Equal(e, u)
+	}
+	return e == u
+}
+
+func TestGolden(t *testing.T) {
+	for _, g := range golden {
+		if b := g.t.Pack(); string(b) != g.b {
+			t.Errorf("Pack(%v) = %x, expected %x", g.t, b, g.b)
+		}
+
+		u, e := tuple.Unpack([]byte(g.b))
+		if e != nil {
+			t.Errorf("Unpack(%x): %v", g.b, e)
+			continue
+		}
+		if !equal(g.t, u) {
+			t.Errorf("Unpack(%x) = %v, expected %v", g.b, u, g.t)
+		}
+	}
+}
+
+func TestUnpackMalformed(t *testing.T) {
+	for _, b := range malformed {
+		if tup, e := tuple.Unpack(b); e == nil {
+			t.Errorf("Unpack(%x) = %v, expected an error", b, tup)
+		}
+	}
+}
+
+// FuzzUnpack checks that Unpack never panics, and that packing is the inverse
+// of unpacking on the encoding of any tuple that Unpack accepts.
+func FuzzUnpack(f *testing.F) {
+	for _, tup := range validTuples {
+		f.Add(tup.Pack())
+	}
+	for _, b := range malformed {
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		tup, e := tuple.Unpack(b)
+
+		var decoded tuple.Tuple
+		d := tuple.NewDecoder(b)
+		for d.Next() {
+			decoded = append(decoded, d.Element())
+		}
+		if (e == nil) != (d.Err() == nil) {
+			t.Fatalf("Unpack(%x) returned %v, but Decoder returned %v", b, e, d.Err())
+		}
+
+		if e != nil || tup.HasIncompleteVersionstamp() {
+			return
+		}
+
+		p, e := tup.PackWithError()
+		if e != nil {
+			t.Fatalf("PackWithError(Unpack(%x)) failed: %v", b, e)
+		}
+		if a := tuple.AppendPack([]byte("prefix"), decoded); !bytes.Equal(a[6:], p) {
+			t.Fatalf("AppendPack of decoded elements of %x = %x, expected %x", b, a[6:], p)
+		}
+
+		tup2, e := tuple.Unpack(p)
+		if e != nil {
+			t.Fatalf("Unpack(%x) failed: %v", p, e)
+		}
+		if p2 := tup2.Pack(); !bytes.Equal(p, p2) {
+			t.Fatalf("Pack(Unpack(%x)) = %x", p, p2)
+		}
+	})
+}
+
+func TestPackWithErrorIndex(t *testing.T) {
+	huge := new(big.Int).Lsh(big.NewInt(1), 8*255)
+
+	tests := []struct {
+		t tuple.Tuple
+		err string
+	}{
+		{tuple.Tuple{1, huge}, "integer at index 1 is too large to encode (magnitude of 256 bytes, at most 255 allowed)"},
+		{tuple.Tuple{"a", "b", tuple.Tuple{huge}}, "integer at index 2 is too large to encode (magnitude of 256 bytes, at most 255 allowed)"},
+		{tuple.Tuple{1, *new(big.Int).Neg(huge)}, "integer at index 1 is too large to encode (magnitude of 256 bytes, at most 255 allowed)"},
+		{tuple.Tuple{1, struct{}{}}, "unencodable element at index 1 ({}, type struct {})"},
+		{tuple.Tuple{tuple.Tuple{1}, tuple.Tuple{"a", struct{}{}}}, "unencodable element at index 1 ({}, type struct {})"},
+	}
+
+	for _, tt := range tests {
+		if _, e := tt.t.PackWithError(); e == nil || e.Error() != tt.err {
+			t.Errorf("PackWithError(%v) returned %v, expected %q", tt.t, e, tt.err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tup := range validTuples {
+		if e := tuple.Validate(tup); e != nil {
+			t.Errorf("Validate(%v): %v", tup, e)
+		}
+	}
+	if e := tuple.Validate(tuple.Tuple{"a", tuple.Tuple{tuple.IncompleteVersionstamp(1)}}); e != nil {
+		t.Errorf("Validate of a tuple with an incomplete versionstamp: %v", e)
+	}
+
+	tests := []struct {
+		t tuple.Tuple
+		index int
+		element tuple.TupleElement
+	}{
+		{tuple.Tuple{struct{}{}}, 0, struct{}{}},
+		{tuple.Tuple{1, "a", int8(3)}, 2, int8(3)},
+		{tuple.Tuple{nil, tuple.Tuple{1, tuple.Tuple{map[string]int(nil)}}}, 1, map[string]int(nil)},
+	}
+
+	for _, tt := range tests {
+		e := tuple.Validate(tt.t)
+		ut, ok := e.(tuple.ErrUnsupportedType)
+		if !ok || ut.Index != tt.index || fmt.Sprint(ut.Element) != fmt.Sprint(tt.element) {
+			t.Errorf("Validate(%v) returned %#v, expected ErrUnsupportedType{%d, %v}", tt.t, e, tt.index, tt.element)
+		}
+	}
+
+	huge := new(big.Int).Lsh(big.NewInt(1), 8*255)
+	if e := tuple.Validate(tuple.Tuple{"a", tuple.Tuple{huge}}); e == nil || e.Error() != "integer at index 1 is too large to encode (magnitude of 256 bytes, at most 255 allowed)" {
+		t.Errorf("Validate of a tuple with a too large integer returned %v", e)
+	}
+}
+
+// incomplete is the encoding of an incomplete versionstamp with the provided
+// user version.
+func incomplete(userVersion byte) []byte {
+	return append([]byte{0x33}, append(bytes.Repeat([]byte{0xFF}, 10), 0x00, userVersion)...)
+}
+
+func TestPackWithVersionstamp(t *testing.T) {
+	stamp := tuple.IncompleteVersionstamp(7)
+
+	tests := []struct {
+		version int
+		prefix []byte
+		t tuple.Tuple
+		expected []byte
+	}{
+		{710, nil, tuple.Tuple{stamp}, append(incomplete(7), 0x01, 0x00, 0x00, 0x00)},
+		{710, []byte("p"), tuple.Tuple{"a", stamp}, append(append([]byte("p\x02a\x00"), incomplete(7)...), 0x05, 0x00, 0x00, 0x00)},
+		{710, nil, tuple.Tuple{tuple.Tuple{nil, stamp}}, append(append([]byte{0x05, 0x00, 0xFF}, incomplete(7)...), 0x00, 0x04, 0x00, 0x00, 0x00)},
+		{710, bytes.Repeat([]byte{'p'}, 0x10000), tuple.Tuple{stamp}, append(append(bytes.Repeat([]byte{'p'}, 0x10000), incomplete(7)...), 0x01, 0x00, 0x01, 0x00)},
+		{520, []byte("p"), tuple.Tuple{stamp, 1}, append(append([]byte("p"), incomplete(7)...), 0x15, 0x01, 0x02, 0x00, 0x00, 0x00)},
+		{510, []byte("p"), tuple.Tuple{"a", stamp}, append(append([]byte("p\x02a\x00"), incomplete(7)...), 0x05, 0x00)},
+		{200, nil, tuple.Tuple{stamp}, append(incomplete(7), 0x01, 0x00)},
+	}
+
+	for _, tt := range tests {
+		tuple.SelectAPIVersion(t, tt.version)
+		b, e := tt.t.PackWithVersionstamp(tt.prefix)
+		if e != nil {
+			t.Errorf("PackWithVersionstamp(%v) at API version %d: %v", tt.t, tt.version, e)
+			continue
+		}
+		if !bytes.Equal(b, tt.expected) {
+			t.Errorf("PackWithVersionstamp(%v) at API version %d = %x, expected %x", tt.t, tt.version, b, tt.expected)
+		}
+	}
+}
+
+func TestPackWithVersionstampErrors(t *testing.T) {
+	complete := tuple.Versionstamp{[10]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 0}
+
+	tests := []struct {
+		version int
+		prefix []byte
+		t tuple.Tuple
+		err string
+	}{
+		{710, nil, tuple.Tuple{}, "tuple does not contain an incomplete versionstamp"},
+		{710, nil, tuple.Tuple{"a", complete}, "tuple does not contain an incomplete versionstamp"},
+		{710, nil, tuple.Tuple{tuple.IncompleteVersionstamp(1), tuple.IncompleteVersionstamp(2)}, "tuple contains 2 incomplete versionstamps (only one is allowed)"},
+		{710, nil, tuple.Tuple{tuple.IncompleteVersionstamp(1), tuple.Tuple{tuple.IncompleteVersionstamp(2)}}, "tuple contains 2 incomplete versionstamps (only one is allowed)"},
+		{510, bytes.Repeat([]byte{'p'}, 0x10000), tuple.Tuple{tuple.IncompleteVersionstamp(1)}, "versionstamp offset 65537 is too large for API version 510"},
+		{710, nil, tuple.Tuple{tuple.IncompleteVersionstamp(1), struct{}{}}, "unencodable element at index 1 ({}, type struct {})"},
+	}
+
+	for _, tt := range tests {
+		tuple.SelectAPIVersion(t, tt.version)
+		if _, e := tt.t.PackWithVersionstamp(tt.prefix); e == nil || e.Error() != tt.err {
+			t.Errorf("PackWithVersionstamp(%v) at API version %d returned %v, expected %q", tt.t, tt.version, e, tt.err)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tuples := append([]tuple.Tuple{{"a"}, {"a", nil}, {int64(-1)}, {1, "b"}, {tuple.Tuple{}}}, validTuples...)
+	for _, a := range tuples {
+		for _, b := range tuples {
+			if c, expected := tuple.Compare(a, b), bytes.Compare(a.Pack(), b.Pack()); c != expected {
+				t.Errorf("Compare(%v, %v) = %d, expected %d", a, b, c, expected)
+			}
+		}
+	}
+}
+
+// contains returns true if the key encoding t is within kr.
+func contains(kr fdb.KeyRange, t tuple.Tuple) bool {
+	k := t.Pack()
+	return bytes.Compare(k, kr.Begin.FDBKey()) >= 0 && bytes.Compare(k, kr.End.FDBKey()) < 0
+}
+
+func TestRange(t *testing.T) {
+	kr := tuple.Tuple{"idx"}.Range()
+	for _, tup := range []tuple.Tuple{{"idx"}, {"idx", nil}, {"idx", 1, "x"}} {
+		if !contains(kr, tup) {
+			t.Errorf("Range() of (idx) does not contain %v", tup)
+		}
+	}
+	for _, tup := range []tuple.Tuple{{}, {"id"}, {"idy"}, {[]byte("idx")}} {
+		if contains(kr, tup) {
+			t.Errorf("Range() of (idx) contains %v", tup)
+		}
+	}
+}
+
+func TestRangeBetween(t *testing.T) {
+	kr := tuple.RangeBetween(tuple.Tuple{"idx"}, 10, 20)
+	for _, tup := range []tuple.Tuple{{"idx", 10}, {"idx", 10, "x"}, {"idx", 19, nil}} {
+		if !contains(kr, tup) {
+			t.Errorf("RangeBetween((idx), 10, 20) does not contain %v", tup)
+		}
+	}
+	for _, tup := range []tuple.Tuple{{"idx"}, {"idx", 9, "x"}, {"idx", 20}, {"idx", 20, "x"}, {"idy", 15}} {
+		if contains(kr, tup) {
+			t.Errorf("RangeBetween((idx), 10, 20) contains %v", tup)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	type ints struct {
+		I int
+		I8 int8
+		I16 int16
+		I32 int32
+		I64 int64
+	}
+	type uints struct {
+		U uint
+		U8 uint8
+		U16 uint16
+		U32 uint32
+		U64 uint64
+	}
+	type scalars struct {
+		B bool
+		S string
+		F32 float32
+		F64 float64
+		Bytes []byte
+	}
+	type elements struct {
+		T tuple.Tuple
+		ID tuple.UUID
+		V tuple.Versionstamp
+		Any interface{}
+		None interface{}
+	}
+	type point struct {
+		X, Y int32
+	}
+	type pointers struct {
+		I *int
+		S *string
+		P *point
+		Nil *int
+		NilP *point
+	}
+	type nested struct {
+		Name string
+		At point
+		Inner struct {
+			P point
+			Tags tuple.Tuple
+		}
+	}
+	type bigs struct {
+		V big.Int
+		P *big.Int
+		Nil *big.Int
+	}
+	type times struct {
+		T time.Time
+		P *time.Time
+	}
+	type tagged struct {
+		B string `fdb:"1"`
+		A int32 `fdb:"0"`
+		Skipped int `fdb:"-"`
+		unexported int
+	}
+
+	i, str := -7, "pointed"
+	at := time.Date(2015, 6, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name string
+		v interface{}
+	}{
+		{"signed integers", &ints{-1, math.MinInt8, math.MaxInt16, math.MinInt32, math.MaxInt64}},
+		{"unsigned integers", &uints{1, math.MaxUint8, math.MaxUint16, math.MaxUint32, math.MaxUint64}},
+		{"large uint64", &uints{U64: math.MaxInt64 + 1}},
+		{"scalars", &scalars{true, "héllo", 1.5, -3.14, []byte("a\x00b")}},
+		{"elements", &elements{tuple.Tuple{"a", int64(1), nil}, tuple.UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, tuple.Versionstamp{[10]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 11}, "any", nil}},
+		{"pointers", &pointers{&i, &str, &point{3, -4}, nil, nil}},
+		{"nested structs", &nested{Name: "n", At: point{1, 2}, Inner: struct {
+			P point
+			Tags tuple.Tuple
+		}{point{-1, -2}, tuple.Tuple{"x"}}}},
+		{"big integers", &bigs{*bigInt("-0x123456789abcdef0123"), bigInt("0x10000000000000000"), nil}},
+		{"small big integers", &bigs{*big.NewInt(-5), big.NewInt(42), nil}},
+		{"times", &times{at, &at}},
+		{"zero time", &times{time.Unix(0, 0).UTC(), nil}},
+		{"tagged", &tagged{B: "b", A: 7}},
+	}
+
+	for _, tt := range tests {
+		b, e := tuple.Marshal(tt.v)
+		if e != nil {
+			t.Errorf("%s: Marshal returned %v", tt.name, e)
+			continue
+		}
+
+		/* A struct, rather than a pointer to one, encodes the same */
+		if bv, e := tuple.Marshal(reflect.ValueOf(tt.v).Elem().Interface()); e != nil || !bytes.Equal(bv, b) {
+			t.Errorf("%s: Marshal of the struct returned %x (%v), expected %x", tt.name, bv, e, b)
+		}
+
+		u := reflect.New(reflect.TypeOf(tt.v).Elem())
+		if e := tuple.Unmarshal(b, u.Interface()); e != nil {
+			t.Errorf("%s: Unmarshal returned %v", tt.name, e)
+			continue
+		}
+		if !reflect.DeepEqual(u.Interface(), tt.v) {
+			t.Errorf("%s: unmarshalled %+v, expected %+v", tt.name, u.Elem(), reflect.ValueOf(tt.v).Elem())
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	type partlyTagged struct {
+		A int `fdb:"0"`
+		B int
+	}
+	type gap struct {
+		A int `fdb:"0"`
+		B int `fdb:"2"`
+	}
+	type badTag struct {
+		A int `fdb:"first"`
+	}
+	type unsupported struct {
+		C chan int
+	}
+	type nested struct {
+		N unsupported
+	}
+
+	tests := []struct {
+		v interface{}
+		err string
+	}{
+		{nil, "cannot marshal nil as a tuple (expected a struct or a pointer to a struct)"},
+		{(*partlyTagged)(nil), "cannot marshal *tuple_test.partlyTagged as a tuple (expected a struct or a pointer to a struct)"},
+		{42, "cannot marshal int as a tuple (expected a struct or a pointer to a struct)"},
+		{partlyTagged{}, "either all or none of the encoded fields of tuple_test.partlyTagged must have an fdb tag giving their position"},
+		{gap{}, "the fdb tags of tuple_test.gap must number the positions of its fields from 0 without gaps or repetition"},
+		{badTag{}, "invalid fdb tag \"first\" on field A of tuple_test.badTag (expected a tuple position or \"-\")"},
+		{unsupported{}, "cannot marshal field C of tuple_test.unsupported: unsupported type chan int"},
+		{&nested{}, "cannot marshal field N of tuple_test.nested: cannot marshal field C of tuple_test.unsupported: unsupported type chan int"},
+		{struct{ T time.Time }{time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)}, "cannot marshal field T of struct { T time.Time }: time 3000-01-01 00:00:00 +0000 UTC cannot be represented in Unix nanoseconds"},
+	}
+
+	for _, tt := range tests {
+		if _, e := tuple.Marshal(tt.v); e == nil || e.Error() != tt.err {
+			t.Errorf("Marshal(%#v) returned %v, expected %q", tt.v, e, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type small struct {
+		I int8
+		U uint16
+	}
+	type typed struct {
+		S string
+		T time.Time
+	}
+
+	tests := []struct {
+		t tuple.Tuple
+		v interface{}
+		err string
+	}{
+		{tuple.Tuple{128, 0}, &small{}, "cannot unmarshal element 0 into field I of tuple_test.small: cannot decode 128 (int64) as int8"},
+		{tuple.Tuple{0, -1}, &small{}, "cannot unmarshal element 1 into field U of tuple_test.small: cannot decode -1 as uint16"},
+		{tuple.Tuple{0, 65536}, &small{}, "cannot unmarshal element 1 into field U of tuple_test.small: 65536 overflows uint16"},
+		{tuple.Tuple{0, uint64(math.MaxUint64)}, &small{}, "cannot unmarshal element 1 into field U of tuple_test.small: 18446744073709551615 overflows uint16"},
+		{tuple.Tuple{"a", 1}, &small{}, "cannot unmarshal element 0 into field I of tuple_test.small: cannot decode a (string) as int8"},
+		{tuple.Tuple{1, "now"}, &typed{}, "cannot unmarshal element 0 into field S of tuple_test.typed: cannot decode int64 as string"},
+		{tuple.Tuple{"a", "now"}, &typed{}, "cannot unmarshal element 1 into field T of tuple_test.typed: cannot decode string as time.Time"},
+		{tuple.Tuple{0}, &small{}, "cannot unmarshal tuple of 1 elements into tuple_test.small (expected 2 elements)"},
+		{tuple.Tuple{0, 0}, small{}, "cannot unmarshal tuple into tuple_test.small (expected a non-nil pointer to a struct)"},
+		{tuple.Tuple{0, 0}, (*small)(nil), "cannot unmarshal tuple into *tuple_test.small (expected a non-nil pointer to a struct)"},
+		{tuple.Tuple{0, 0}, nil, "cannot unmarshal tuple into <nil> (expected a non-nil pointer to a struct)"},
+	}
+
+	for _, tt := range tests {
+		if e := tuple.Unmarshal(tt.t.Pack(), tt.v); e == nil || e.Error() != tt.err {
+			t.Errorf("Unmarshal(%v) into %T returned %v, expected %q", tt.t, tt.v, e, tt.err)
+		}
+	}
+
+	if e := tuple.Unmarshal([]byte{0x15}, &small{}); e == nil {
+		t.Errorf("Unmarshal of a malformed tuple returned no error")
+	}
+}
+
+func ExampleMarshal() {
+	type Event struct {
+		Kind string `fdb:"1"`
+		At time.Time `fdb:"0"`
+		Seq uint16 `fdb:"2"`
+		Note string `fdb:"-"`
+	}
+
+	b, e := tuple.Marshal(Event{"login", time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC), 3, "ignored"})
+	if e != nil {
+		fmt.Printf("Marshal failed: %v\n", e)
+		return
+	}
+
+	t, _ := tuple.Unpack(b)
+	fmt.Println(t)
+
+	var ev Event
+	if e := tuple.Unmarshal(b, &ev); e != nil {
+		fmt.Printf("Unmarshal failed: %v\n", e)
+		return
+	}
+	fmt.Println(ev.At, ev.Kind, ev.Seq)
+
+	// Output:
+	// [1433116800000000000 login 3]
+	// 2015-06-01 00:00:00 +0000 UTC login 3
+}
+
+// benchTuple is a typical key: a short string, a few integers and a UUID.
+var benchTuple = tuple.Tuple{"users", int64(1234567), "alice@example.com", int64(-42), tuple.UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}
+
+func BenchmarkPack(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		benchTuple.Pack()
+	}
+}
+
+func BenchmarkAppendPack(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for i := 0; i < b.N; i++ {
+		buf = tuple.AppendPack(buf[:0], benchTuple)
+	}
+}
+
+func BenchmarkUnpack(b *testing.B) {
+	p := benchTuple.Pack()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tuple.Unpack(p)
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	p := benchTuple.Pack()
+	var d tuple.Decoder
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d.Reset(p)
+		for d.Next() {
+			switch d.Kind() {
+			case tuple.KindString:
+				d.Bytes()
+			case tuple.KindInt:
+				d.Int()
+			case tuple.KindUUID:
+				d.UUID()
+			}
+		}
+	}
+}